@@ -0,0 +1,134 @@
+package im920
+
+import (
+	"context"
+	"fmt"
+)
+
+// Change is one parameter changed by Apply.
+type Change struct {
+	Param string
+	From  any
+	To    any
+}
+
+type ApplyReport struct {
+	Changes []Change
+}
+
+type applyStep struct {
+	change Change
+	do     func() error
+}
+
+// Desired is the state Apply brings the module to. A nil field leaves
+// that parameter as it is; an empty, non-nil RcvIds erases all receive
+// IDs.
+type Desired struct {
+	Node   *Node
+	Ch     *Ch
+	Mode   *Mode
+	Power  *Power
+	CharIO *bool
+	RcvIds []Id
+}
+
+// Apply makes the module's parameters match the non-nil fields of
+// desired, changing only what differs from ReadSettings. With persist,
+// all changes share a single ENWR/DSWR window. Without it, only the
+// receive IDs, which the module cannot register otherwise, are written
+// inside a window of their own, after the other changes. The window is
+// always closed again. The report lists the changes made, also when an
+// error stops Apply part way.
+func (im *IM920) Apply(ctx context.Context, desired Desired, persist bool) (report ApplyReport, err error) {
+	if desired.Mode != nil && *desired.Mode != FAST_MODE && *desired.Mode != LONG_MODE {
+		return report, fmt.Errorf("error: invalid mode (%d)", *desired.Mode)
+	}
+	if desired.Power != nil && (*desired.Power < POWER_MINUS_10DBM || *desired.Power > POWER_10DBM) {
+		return report, fmt.Errorf("error: invalid power level (%d)", *desired.Power)
+	}
+
+	if err = checkRcvIds(desired.RcvIds); err != nil {
+		return
+	}
+
+	cur, err := im.ReadSettingsContext(ctx)
+	if err != nil {
+		return report, fmt.Errorf("error: ReadSettings failed: %w", err)
+	}
+	if desired.CharIO != nil && *desired.CharIO && !cur.CharIO {
+		if err = im.require(CAP_CHAR_IO); err != nil {
+			return
+		}
+	}
+
+	var steps, rcvSteps []applyStep
+	add := func(param string, from, to any, do func() error) {
+		steps = append(steps, applyStep{change: Change{Param: param, From: from, To: to}, do: do})
+	}
+
+	if node := desired.Node; node != nil && *node != cur.Node {
+		add("Node", cur.Node, *node, func() error { return im.setNode(ctx, *node) })
+	}
+	if ch := desired.Ch; ch != nil && *ch != cur.Ch {
+		add("Ch", cur.Ch, *ch, func() error { return im.setCh(ctx, *ch) })
+	}
+	if mode := desired.Mode; mode != nil && *mode != cur.Mode {
+		add("Mode", cur.Mode, *mode, func() error { return im.setCommMode(ctx, *mode) })
+	}
+	if power := desired.Power; power != nil && *power != cur.Power {
+		add("Power", cur.Power, *power, func() error { return im.setTxPower(ctx, *power) })
+	}
+	if charIO := desired.CharIO; charIO != nil && *charIO != cur.CharIO {
+		add("CharIO", cur.CharIO, *charIO, func() error {
+			if err := im.setCharIOMode(ctx, *charIO); err != nil {
+				return err
+			}
+			if persist {
+				im.saveCharIO(*charIO)
+			}
+
+			return nil
+		})
+	}
+
+	if desired.RcvIds != nil {
+		erase, ids := rcvIdChanges(cur.RcvIds, desired.RcvIds)
+		if erase || len(ids) > 0 {
+			rcvSteps = append(rcvSteps, applyStep{
+				change: Change{Param: "RcvIds", From: cur.RcvIds, To: desired.RcvIds},
+				do:     func() error { return im.setRcvIds(ctx, erase, ids) },
+			})
+		}
+	}
+
+	run := func(steps []applyStep) func() error {
+		return func() error {
+			for _, step := range steps {
+				if err := step.do(); err != nil {
+					return err
+				}
+				report.Changes = append(report.Changes, step.change)
+			}
+
+			return nil
+		}
+	}
+
+	if persist {
+		if len(steps)+len(rcvSteps) > 0 {
+			err = im.writeEnable(ctx, true, run(append(steps, rcvSteps...)))
+		}
+		return
+	}
+
+	if err = run(steps)(); err != nil {
+		return
+	}
+	// registering receive IDs always needs ENWR
+	if len(rcvSteps) > 0 {
+		err = im.writeEnable(ctx, true, run(rcvSteps))
+	}
+
+	return
+}
@@ -0,0 +1,121 @@
+package im920
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+const applyDump = "ID:06E5\r\nSTNN:01\r\nSTCH:01\r\nSTRT:1\r\nSTPO:3\r\nDCIO\r\nSRID:0001,0002\r\nRDVR:IM920 Ver.04.00\r\n"
+
+var ApplyTests = []struct {
+	in_desired     Desired
+	in_persist     bool
+	in_respData    [][]byte
+	out_cmds       []string
+	out_changes    []string
+	out_errorIsNil bool
+}{
+	{
+		// nothing to change
+		Desired{Node: ptr[Node](0x01), Ch: ptr[Ch](0x01), RcvIds: []Id{0x0002, 0x0001}}, true,
+		[][]byte{[]byte(applyDump)},
+		[]string{"RPRM \r\n"},
+		nil, true,
+	},
+	{
+		Desired{Node: ptr[Node](0x01), Ch: ptr[Ch](0x05), Mode: ptr(LONG_MODE)}, true,
+		[][]byte{[]byte(applyDump), []byte("OK\r\n"), []byte("OK\r\n"), []byte("OK\r\n"), []byte("OK\r\n")},
+		[]string{"RPRM \r\n", "ENWR \r\n", "STCH 05\r\n", "STRT 02\r\n", "DSWR \r\n"},
+		[]string{"Ch", "Mode"}, true,
+	},
+	{
+		// without persist, no window unless receive IDs change
+		Desired{Node: ptr[Node](0x01), Power: ptr(POWER_0DBM)}, false,
+		[][]byte{[]byte(applyDump), []byte("OK\r\n")},
+		[]string{"RPRM \r\n", "STPO 2\r\n"},
+		[]string{"Power"}, true,
+	},
+	{
+		Desired{Node: ptr[Node](0x01), RcvIds: []Id{0x0001, 0x0002, 0x0003}}, false,
+		[][]byte{[]byte(applyDump), []byte("OK\r\n"), []byte("OK\r\n"), []byte("OK\r\n")},
+		[]string{"RPRM \r\n", "ENWR \r\n", "SRID 0003\r\n", "DSWR \r\n"},
+		[]string{"RcvIds"}, true,
+	},
+	{
+		Desired{Node: ptr[Node](0x01), RcvIds: []Id{0x0002}}, false,
+		[][]byte{[]byte(applyDump), []byte("OK\r\n"), []byte("OK\r\n"), []byte("OK\r\n"), []byte("OK\r\n")},
+		[]string{"RPRM \r\n", "ENWR \r\n", "ERID \r\n", "SRID 0002\r\n", "DSWR \r\n"},
+		[]string{"RcvIds"}, true,
+	},
+	{
+		// without persist, Ch is not written to memory along with the
+		// receive IDs
+		Desired{Node: ptr[Node](0x01), Ch: ptr[Ch](0x05), RcvIds: []Id{0x0002}}, false,
+		[][]byte{[]byte(applyDump), []byte("OK\r\n"), []byte("OK\r\n"), []byte("OK\r\n"), []byte("OK\r\n"), []byte("OK\r\n")},
+		[]string{"RPRM \r\n", "STCH 05\r\n", "ENWR \r\n", "ERID \r\n", "SRID 0002\r\n", "DSWR \r\n"},
+		[]string{"Ch", "RcvIds"}, true,
+	},
+	{
+		// DSWR is issued even though STRT fails
+		Desired{Node: ptr[Node](0x02), Mode: ptr(LONG_MODE)}, true,
+		[][]byte{[]byte(applyDump), []byte("OK\r\n"), []byte("OK\r\n"), []byte("NG\r\n"), []byte("OK\r\n")},
+		[]string{"RPRM \r\n", "ENWR \r\n", "STNN 02\r\n", "STRT 02\r\n", "DSWR \r\n"},
+		[]string{"Node"}, false,
+	},
+	{
+		// only the named parameter is touched, whatever the module's
+		// node and character I/O mode
+		Desired{Ch: ptr[Ch](0x05)}, false,
+		[][]byte{[]byte("ID:06E5\r\nSTNN:07\r\nSTCH:01\r\nSTRT:1\r\nSTPO:3\r\nECIO\r\nSRID:0001\r\nRDVR:IM920 Ver.04.00\r\n"), []byte("OK\r\n")},
+		[]string{"RPRM \r\n", "STCH 05\r\n"},
+		[]string{"Ch"}, true,
+	},
+	{
+		Desired{CharIO: ptr(true)}, false,
+		[][]byte{[]byte(applyDump), []byte("OK\r\n")},
+		[]string{"RPRM \r\n", "ECIO \r\n"},
+		[]string{"CharIO"}, true,
+	},
+	{
+		Desired{Power: ptr[Power](7)}, true,
+		nil,
+		nil,
+		nil, false,
+	},
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestApply(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	for i, tt := range ApplyTests {
+		serial.takeWritedAll()
+		serial.setRespData(tt.in_respData...)
+		report, err := im.Apply(context.Background(), tt.in_desired, tt.in_persist)
+		if (err == nil) != tt.out_errorIsNil {
+			t.Errorf("[%d]Apply() => %v, want errorIsNil = %v", i, err, tt.out_errorIsNil)
+		}
+
+		var changes []string
+		for _, c := range report.Changes {
+			changes = append(changes, c.Param)
+		}
+		if !reflect.DeepEqual(changes, tt.out_changes) {
+			t.Errorf("[%d]Apply() => changes %v, want %v", i, changes, tt.out_changes)
+		}
+
+		got := writesOf(serial.takeWritedAll())
+		if len(got) == 0 {
+			got = nil
+		}
+		if !reflect.DeepEqual(got, tt.out_cmds) {
+			t.Errorf("[%d]Apply() => wrote %q, want %q", i, got, tt.out_cmds)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package im920
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const probeTimeout = 300 * time.Millisecond
+
+// baudRates lists the rates the IM920 supports, indexed by their SBRT
+// parameter.
+var baudRates = []int{1200, 2400, 4800, 9600, 19200, 38400, 57600, 115200}
+
+// wireTime is how long n characters take on the wire at baud, with a
+// start and a stop bit each.
+func wireTime(baud, n int) time.Duration {
+	return time.Duration(n*10) * time.Second / time.Duration(baud)
+}
+
+func baudCode(rate int) (string, bool) {
+	for i, v := range baudRates {
+		if v == rate {
+			return strconv.Itoa(i), true
+		}
+	}
+
+	return "", false
+}
+
+// openAuto tries first and then every other supported rate, fastest
+// first, until the module answers RDID.
+func openAuto(open func(baud int) (io.ReadWriteCloser, error), first int, opts ...Option) (*IM920, error) {
+	rates := []int{first}
+	for i := len(baudRates) - 1; i >= 0; i-- {
+		if baudRates[i] != first {
+			rates = append(rates, baudRates[i])
+		}
+	}
+
+	for _, rate := range rates {
+		s, err := open(rate)
+		if err != nil {
+			return &IM920{}, err
+		}
+
+		im := New(s, append(opts, WithBaudRate(rate), WithReopen(open))...)
+		if im.probe() == nil {
+			return im, nil
+		}
+		im.Close()
+	}
+
+	return &IM920{}, ErrBaudNotDetected
+}
+
+// probe checks for an answer at the current rate. The first attempt
+// may only flush garbage left in the module's line buffer by probes at
+// other rates, so RDID is tried twice.
+func (im *IM920) probe() (err error) {
+	if err = im.lock(context.Background()); err != nil {
+		return
+	}
+	defer im.unlock()
+
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+		err = im.confirmLink(ctx)
+		cancel()
+		if err == nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (im *IM920) SetBaudRate(rate int, persist bool) error {
+	return im.SetBaudRateContext(context.Background(), rate, persist)
+}
+
+// SetBaudRateContext switches the module to rate with SBRT, reopens the
+// transport at the new rate and confirms the link with RDID. If the
+// module does not answer at the new rate, the transport is reopened at
+// the old rate and an error is returned.
+func (im *IM920) SetBaudRateContext(ctx context.Context, rate int, persist bool) (err error) {
+	code, ok := baudCode(rate)
+	if !ok {
+		return fmt.Errorf("error: unsupported baud rate (%d)", rate)
+	}
+	if rate > 38400 {
+		if err = im.require(CAP_HIGH_BAUD); err != nil {
+			return
+		}
+	}
+	if im.reopen == nil {
+		return errors.New("error: SetBaudRate needs a transport opened by Open or WithReopen")
+	}
+
+	if lerr := im.lock(ctx); lerr != nil {
+		return lerr
+	}
+	defer im.unlock()
+
+	if err = im.awake(ctx); err != nil {
+		return
+	}
+
+	old := im.BaudRate()
+
+	if persist {
+		ierr := im.issueOK(ctx, "ENWR", "")
+		if ierr != nil {
+			return fmt.Errorf("error: ENWR failed: %w", ierr)
+		}
+		defer func() {
+			ierr := im.issueOK(context.Background(), "DSWR", "")
+			if ierr != nil && err == nil {
+				err = fmt.Errorf("error: DSWR failed: %w", ierr)
+			}
+		}()
+	}
+
+	ierr := im.issueOK(ctx, "SBRT", code)
+	if ierr != nil {
+		return fmt.Errorf("error: SBRT failed: %w", ierr)
+	}
+
+	// from here on the module only understands the new rate
+	rerr := im.reopenPort(rate)
+	if rerr == nil {
+		rerr = im.confirmLink(ctx)
+		if rerr == nil {
+			return nil
+		}
+	}
+
+	if ferr := im.reopenPort(old); ferr != nil {
+		return fmt.Errorf("error: switch to %d failed: %v, and back to %d failed: %w", rate, rerr, old, ferr)
+	}
+	if ferr := im.confirmLink(context.Background()); ferr != nil {
+		return fmt.Errorf("error: switch to %d failed: %v, and no response at %d: %w", rate, rerr, old, ferr)
+	}
+
+	return fmt.Errorf("error: switch to %d failed: %w", rate, rerr)
+}
+
+// confirmLink checks that the module answers RDID with a well-formed
+// ID. The caller must hold the command lock.
+func (im *IM920) confirmLink(ctx context.Context) error {
+	resp, err := im.issue(ctx, "RDID", "")
+	if err != nil {
+		return err
+	}
+
+	if _, derr := strToUint16(strings.TrimSuffix(string(resp), "\r\n")); derr != nil {
+		return &CommandError{Cmd: "RDID", Raw: resp, Err: fmt.Errorf("%w: %s", ErrUnexpectedResponse, derr)}
+	}
+
+	return nil
+}
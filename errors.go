@@ -0,0 +1,51 @@
+package im920
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrNG                 = errors.New("NG response")
+	ErrBusyTimeout        = errors.New("busy wait timed out")
+	ErrNoResponse         = errors.New("no response")
+	ErrUnexpectedResponse = errors.New("unexpected response")
+	ErrBaudNotDetected    = errors.New("baud rate not detected")
+	ErrClosed             = errors.New("closed")
+	ErrNotAcknowledged    = errors.New("not acknowledged")
+	ErrConnReset          = errors.New("connection reset by peer")
+	ErrAsleep             = errors.New("module is asleep")
+	ErrUnsupported        = errors.New("not supported by this firmware")
+)
+
+// CommandError is returned when a command issued to the module fails.
+// Raw holds the response as received, if any.
+type CommandError struct {
+	Cmd   string
+	Param string
+	Raw   []byte
+	Err   error
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("%s: %s (%q)", strings.TrimSpace(e.Cmd+" "+e.Param), e.Err, e.Raw)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// FrameError is returned when a received line cannot be parsed.
+type FrameError struct {
+	Line string
+	Err  error
+}
+
+func (e *FrameError) Error() string {
+	return fmt.Sprintf("error: invalid frame (%q): %s", e.Line, e.Err)
+}
+
+func (e *FrameError) Unwrap() error {
+	return e.Err
+}
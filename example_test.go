@@ -6,7 +6,7 @@ import (
 	"time"
 )
 
-func ExampleRead() {
+func ExampleIM920_Read() {
 	// This test will not be run, it has no "Output:" comment.
 	c := &im920.Config{Name: "COM4", ReadTimeout: 1 * time.Second}
 	im, err := im920.Open(c)
@@ -28,3 +28,24 @@ func ExampleRead() {
 		fmt.Printf("Read Info: %v\n", im.LastReadInfo())
 	}
 }
+
+func ExampleIM920_ReadPacket() {
+	// This test will not be run, it has no "Output:" comment.
+	c := &im920.Config{Name: "COM4", ReadTimeout: 1 * time.Second}
+	im, err := im920.Open(c)
+	if err != nil {
+		fmt.Printf("Failed to open: %s\n", err)
+		return
+	}
+	defer im.Close()
+
+	for i := 0; i < 20; i++ {
+		pkt, err := im.ReadPacket()
+		if err != nil {
+			fmt.Printf("Failed to read: %s\n", err)
+			continue
+		}
+
+		fmt.Printf("%v from %04X (RSSI %v): %v\n", pkt.Time, pkt.FromId, pkt.FromRssi, pkt.Data)
+	}
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"io"
 	"log"
 	"runtime"
 	"time"
@@ -10,7 +9,13 @@ import (
 	"github.com/tomoya0x00/go-im920"
 )
 
-// TODO: BusyCheck
+// BUSY and RESET of the module wired to GPIO17 and GPIO27 of a Raspberry Pi
+const (
+	gpioChip = "/dev/gpiochip0"
+	busyPin  = 17
+	resetPin = 27
+)
+
 func main() {
 	serialName := "/dev/ttyAMA0" // for Raspberry Pi
 	if runtime.GOOS == "windows" {
@@ -24,6 +29,14 @@ func main() {
 	}
 	defer im.Close()
 
+	g, err := im920.OpenGPIO(gpioChip, busyPin, resetPin)
+	if err != nil {
+		fmt.Printf("Failed to open GPIO, BUSY is not checked: %s\n", err)
+	} else {
+		defer g.Close()
+		im.IsBusyLine(g)
+	}
+
 	id, err := im.GetId()
 	if err != nil {
 		fmt.Printf("Failed to GetId: %s\n", err)
@@ -38,15 +51,17 @@ func main() {
 		fmt.Printf("RIDS: %v\n", rids)
 	}
 
-	rch := NewReceiver(im)
+	rch, cancel := im.Subscribe(100)
+	defer cancel()
+
 	b := make([]byte, 1)
 	b[0] = 0
 
 	for {
 		select {
 		case r := <-rch:
-			fmt.Printf("Read %v\n", r)
-			fmt.Printf("ReadInfo %v\n", im.LastReadInfo())
+			fmt.Printf("Read %v\n", r.Data)
+			fmt.Printf("ReadInfo %v\n", r.ReadInfo)
 		default:
 			fmt.Printf("Write 0x%02x\n", b[0])
 			_, err = im.Write(b)
@@ -59,25 +74,3 @@ func main() {
 		}
 	}
 }
-
-func NewReceiver(im *im920.IM920) <-chan []byte {
-	ch := make(chan []byte, 100)
-	go receiver(im, ch)
-
-	return ch
-}
-
-func receiver(im *im920.IM920, ch chan<- []byte) {
-	buf := make([]byte, 256)
-	for {
-		time.Sleep(100 * time.Millisecond)
-		n, err := im.Read(buf)
-		if err != nil && err != io.EOF {
-			fmt.Printf("Read error:%s\n", err)
-			continue
-		}
-		if n > 0 {
-			ch <- buf[:n]
-		}
-	}
-}
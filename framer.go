@@ -0,0 +1,101 @@
+package im920
+
+import (
+	"bytes"
+)
+
+const maxLineLen = 256
+
+// Framer splits the byte stream from the module into lines. Lines end
+// in CRLF; a bare LF is accepted as well, since noise on the UART may
+// eat the CR.
+//
+// The module only ever sends printable ASCII, so Framer resyncs after
+// noise by discarding everything up to the last unprintable byte of a
+// line, and by dropping a line that grows past the maximum length
+// without a terminator.
+type Framer struct {
+	buf       []byte
+	maxLen    int
+	skipping  bool
+	discarded int
+}
+
+func NewFramer() *Framer {
+	return &Framer{maxLen: maxLineLen}
+}
+
+// Write buffers p. It never fails.
+func (f *Framer) Write(p []byte) (n int, err error) {
+	f.buf = append(f.buf, p...)
+
+	return len(p), nil
+}
+
+// Next returns the next complete line without its terminator, or false
+// if no complete line is buffered yet.
+func (f *Framer) Next() (line string, ok bool) {
+	for {
+		i := bytes.IndexByte(f.buf, '\n')
+		if i < 0 {
+			if len(f.buf) > f.maxLen {
+				f.discarded += len(f.buf)
+				f.buf = f.buf[:0]
+				f.skipping = true
+			}
+			return "", false
+		}
+
+		raw := bytes.TrimSuffix(f.buf[:i], []byte("\r"))
+		skipping := f.skipping
+		f.skipping = false
+
+		if skipping {
+			f.discarded += i + 1
+			f.consume(i + 1)
+			continue
+		}
+
+		clean, dropped := resync(raw)
+		f.discarded += dropped
+		line = string(clean)
+		f.consume(i + 1)
+
+		if dropped > 0 && len(line) == 0 {
+			continue
+		}
+
+		return line, true
+	}
+}
+
+func (f *Framer) consume(n int) {
+	f.buf = append(f.buf[:0], f.buf[n:]...)
+}
+
+// Discarded returns the number of bytes dropped as noise so far.
+func (f *Framer) Discarded() int {
+	return f.discarded
+}
+
+// Buffered returns the length of the partial line buffered so far.
+func (f *Framer) Buffered() int {
+	return len(f.buf)
+}
+
+// Reset drops any buffered partial line, counting it as discarded.
+func (f *Framer) Reset() {
+	f.discarded += len(f.buf)
+	f.buf = f.buf[:0]
+	f.skipping = false
+}
+
+func resync(raw []byte) (clean []byte, dropped int) {
+	for i := len(raw) - 1; i >= 0; i-- {
+		if raw[i] < 0x20 || raw[i] > 0x7e {
+			return raw[i+1:], i + 1
+		}
+	}
+
+	return raw, 0
+}
@@ -0,0 +1,96 @@
+package im920
+
+import (
+	"reflect"
+	"testing"
+)
+
+var FramerTests = []struct {
+	in            []string
+	out           []string
+	out_discarded int
+}{
+	{
+		[]string{"00,06E5,B5:0A,1F,76\r\n"},
+		[]string{"00,06E5,B5:0A,1F,76"}, 0,
+	},
+	{
+		[]string{"00,06E5,B5:0A", ",1F,76\r", "\n"},
+		[]string{"00,06E5,B5:0A,1F,76"}, 0,
+	},
+	{
+		[]string{"00,06E5,B5:0A\r\n00,06E5,B5:0B\r\nOK\r\n"},
+		[]string{"00,06E5,B5:0A", "00,06E5,B5:0B", "OK"}, 0,
+	},
+	{
+		[]string{"OK\n"},
+		[]string{"OK"}, 0,
+	},
+	{
+		[]string{"\r\n"},
+		[]string{""}, 0,
+	},
+	{
+		[]string{"OK"},
+		nil, 0,
+	},
+	{
+		// a received line is not complete without its terminator
+		[]string{"00,06E5,B5:0A,1F,76"},
+		nil, 0,
+	},
+	{
+		[]string{"\x8f\xf0OK\r\n"},
+		[]string{"OK"}, 2,
+	},
+	{
+		[]string{"\x00\r\nOK\r\n"},
+		[]string{"OK"}, 1,
+	},
+	{
+		[]string{"O\rK\r\n"},
+		[]string{"K"}, 2,
+	},
+	{
+		[]string{string(make([]byte, maxLineLen+1)), "garbage\r\nOK\r\n"},
+		[]string{"OK"}, maxLineLen + 1 + len("garbage\r\n"),
+	},
+}
+
+func TestFramer(t *testing.T) {
+	for i, tt := range FramerTests {
+		f := NewFramer()
+		var lines []string
+		for _, v := range tt.in {
+			f.Write([]byte(v))
+			for {
+				line, ok := f.Next()
+				if !ok {
+					break
+				}
+				lines = append(lines, line)
+			}
+		}
+		if !reflect.DeepEqual(lines, tt.out) {
+			t.Errorf("[%d]Next() => %q, want %q", i, lines, tt.out)
+		}
+		if f.Discarded() != tt.out_discarded {
+			t.Errorf("[%d]Discarded() => %v, want %v", i, f.Discarded(), tt.out_discarded)
+		}
+	}
+}
+
+func TestFramerReset(t *testing.T) {
+	f := NewFramer()
+	f.Write([]byte("00,06E5,B5:0A"))
+	f.Reset()
+	f.Write([]byte("OK\r\n"))
+
+	line, ok := f.Next()
+	if !ok || line != "OK" {
+		t.Errorf("Next() after Reset() => %q, %v, want %q, true", line, ok, "OK")
+	}
+	if f.Discarded() != len("00,06E5,B5:0A") {
+		t.Errorf("Discarded() => %v, want %v", f.Discarded(), len("00,06E5,B5:0A"))
+	}
+}
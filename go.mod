@@ -0,0 +1,7 @@
+module github.com/tomoya0x00/go-im920
+
+go 1.20
+
+require github.com/tarm/serial v0.0.0-20180830185346-98f6abe2eb07
+
+require golang.org/x/sys v0.9.0 // indirect
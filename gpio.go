@@ -0,0 +1,186 @@
+package im920
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	resetPulse = 10 * time.Millisecond
+	resetBoot  = 100 * time.Millisecond
+)
+
+// GPIOChip requests lines of a GPIO chip. OpenGPIOChip returns one for
+// a /dev/gpiochipN character device.
+type GPIOChip interface {
+	// RequestInput requests offset as an input that reports edges in
+	// both directions.
+	RequestInput(offset int, consumer string) (GPIOLine, error)
+	// RequestOutput requests offset as an output driven to value.
+	RequestOutput(offset int, consumer string, value int) (GPIOLine, error)
+	Close() error
+}
+
+type GPIOLine interface {
+	Value() (int, error)
+	SetValue(value int) error
+	// WaitEdge blocks until the next edge event. It fails once the line
+	// is closed.
+	WaitEdge() error
+	Close() error
+}
+
+// GPIO watches the BUSY pin of the module and drives its RESET pin. BUSY
+// is high while the module is busy; RESET is active low.
+type GPIO struct {
+	chip    GPIOChip
+	owned   bool
+	busy    GPIOLine
+	reset   GPIOLine
+	m       sync.Mutex
+	changed chan struct{}
+	done    chan struct{}
+}
+
+var _ BusyLine = (*GPIO)(nil)
+
+// OpenGPIO opens the GPIO chip at path, e.g. "/dev/gpiochip0", and
+// requests the lines at offsets busy and reset. A negative reset leaves
+// RESET alone.
+func OpenGPIO(path string, busy, reset int) (*GPIO, error) {
+	chip, err := OpenGPIOChip(path)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := NewGPIO(chip, busy, reset)
+	if err != nil {
+		chip.Close()
+		return nil, err
+	}
+	g.owned = true
+
+	return g, nil
+}
+
+// NewGPIO is like OpenGPIO on a chip that is already open. Closing the
+// GPIO does not close chip.
+func NewGPIO(chip GPIOChip, busy, reset int) (*GPIO, error) {
+	g := &GPIO{
+		chip:    chip,
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	var err error
+	g.busy, err = chip.RequestInput(busy, "im920-busy")
+	if err != nil {
+		return nil, fmt.Errorf("error: request BUSY line %d failed: %w", busy, err)
+	}
+
+	if reset >= 0 {
+		g.reset, err = chip.RequestOutput(reset, "im920-reset", 1)
+		if err != nil {
+			g.busy.Close()
+			return nil, fmt.Errorf("error: request RESET line %d failed: %w", reset, err)
+		}
+	}
+
+	go g.watch()
+
+	return g, nil
+}
+
+func (g *GPIO) watch() {
+	defer close(g.done)
+
+	for g.busy.WaitEdge() == nil {
+		g.m.Lock()
+		close(g.changed)
+		g.changed = make(chan struct{})
+		g.m.Unlock()
+	}
+}
+
+// IsBusy reports whether BUSY is high. A line that cannot be read counts
+// as busy, so that commands time out rather than collide.
+func (g *GPIO) IsBusy() bool {
+	v, err := g.busy.Value()
+
+	return err != nil || v != 0
+}
+
+func (g *GPIO) WaitIdle(ctx context.Context) error {
+	for {
+		g.m.Lock()
+		changed := g.changed
+		g.m.Unlock()
+
+		if !g.IsBusy() {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-g.done:
+			return ErrClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Reset pulls RESET low, releases it and waits for the module to boot.
+func (g *GPIO) Reset(ctx context.Context) error {
+	if g.reset == nil {
+		return fmt.Errorf("error: no RESET line")
+	}
+
+	if err := g.reset.SetValue(0); err != nil {
+		return fmt.Errorf("error: assert RESET failed: %w", err)
+	}
+
+	timer := time.NewTimer(resetPulse)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		// never leave the module held in reset
+		g.reset.SetValue(1)
+		return ctx.Err()
+	case <-timer.C:
+	}
+
+	if err := g.reset.SetValue(1); err != nil {
+		return fmt.Errorf("error: release RESET failed: %w", err)
+	}
+
+	timer = time.NewTimer(resetBoot)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+	}
+
+	return nil
+}
+
+func (g *GPIO) Close() error {
+	err := g.busy.Close()
+	<-g.done
+
+	if g.reset != nil {
+		if rerr := g.reset.Close(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	if g.owned {
+		if cerr := g.chip.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,193 @@
+//go:build linux
+
+package im920
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// GPIO character device ABI v2, from <linux/gpio.h>. Every 64-bit field
+// already sits at an 8-byte offset, so the layout is the same on 32-bit
+// platforms.
+const (
+	gpioV2LinesMax        = 64
+	gpioMaxNameSize       = 32
+	gpioV2LineNumAttrsMax = 10
+	gpioV2LineEventSize   = 48
+
+	gpioV2LineFlagInput       = 1 << 2
+	gpioV2LineFlagOutput      = 1 << 3
+	gpioV2LineFlagEdgeRising  = 1 << 4
+	gpioV2LineFlagEdgeFalling = 1 << 5
+
+	gpioV2LineAttrIdOutputValues = 2
+
+	// _IOWR(0xB4, nr, size)
+	gpioV2GetLineIoctl       = 0xC250B407
+	gpioV2LineGetValuesIoctl = 0xC010B40E
+	gpioV2LineSetValuesIoctl = 0xC010B40F
+)
+
+type gpioV2LineAttribute struct {
+	id      uint32
+	padding uint32
+	value   uint64
+}
+
+type gpioV2LineConfigAttribute struct {
+	attr gpioV2LineAttribute
+	mask uint64
+}
+
+type gpioV2LineConfig struct {
+	flags    uint64
+	numAttrs uint32
+	padding  [5]uint32
+	attrs    [gpioV2LineNumAttrsMax]gpioV2LineConfigAttribute
+}
+
+type gpioV2LineRequest struct {
+	offsets         [gpioV2LinesMax]uint32
+	consumer        [gpioMaxNameSize]byte
+	config          gpioV2LineConfig
+	numLines        uint32
+	eventBufferSize uint32
+	padding         [5]uint32
+	fd              int32
+}
+
+type gpioV2LineValues struct {
+	bits uint64
+	mask uint64
+}
+
+type gpioChip struct {
+	f *os.File
+}
+
+// OpenGPIOChip opens a GPIO character device such as /dev/gpiochip0.
+func OpenGPIOChip(path string) (GPIOChip, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error: open %s failed: %w", path, err)
+	}
+
+	return &gpioChip{f: f}, nil
+}
+
+func ioctl(fd uintptr, req uintptr, arg unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+func (c *gpioChip) request(offset int, consumer string, flags uint64, value int) (GPIOLine, error) {
+	var req gpioV2LineRequest
+	req.offsets[0] = uint32(offset)
+	req.numLines = 1
+	copy(req.consumer[:gpioMaxNameSize-1], consumer)
+	req.config.flags = flags
+	if flags&gpioV2LineFlagOutput != 0 {
+		req.config.numAttrs = 1
+		req.config.attrs[0].attr.id = gpioV2LineAttrIdOutputValues
+		req.config.attrs[0].attr.value = uint64(value & 1)
+		req.config.attrs[0].mask = 1
+	}
+
+	var err error
+	cerr := rawControl(c.f, func(fd uintptr) {
+		err = ioctl(fd, gpioV2GetLineIoctl, unsafe.Pointer(&req))
+	})
+	if cerr != nil {
+		return nil, cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error: GPIO_V2_GET_LINE_IOCTL failed: %w", err)
+	}
+
+	// non-blocking, so that Close interrupts a pending WaitEdge
+	if err := syscall.SetNonblock(int(req.fd), true); err != nil {
+		syscall.Close(int(req.fd))
+		return nil, err
+	}
+
+	return &gpioLine{f: os.NewFile(uintptr(req.fd), fmt.Sprintf("gpio-line-%d", offset))}, nil
+}
+
+func (c *gpioChip) RequestInput(offset int, consumer string) (GPIOLine, error) {
+	return c.request(offset, consumer, gpioV2LineFlagInput|gpioV2LineFlagEdgeRising|gpioV2LineFlagEdgeFalling, 0)
+}
+
+func (c *gpioChip) RequestOutput(offset int, consumer string, value int) (GPIOLine, error) {
+	return c.request(offset, consumer, gpioV2LineFlagOutput, value)
+}
+
+func (c *gpioChip) Close() error {
+	return c.f.Close()
+}
+
+type gpioLine struct {
+	f *os.File
+}
+
+func rawControl(f *os.File, fn func(fd uintptr)) error {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	return rc.Control(fn)
+}
+
+func (l *gpioLine) Value() (int, error) {
+	vals := gpioV2LineValues{mask: 1}
+
+	var err error
+	cerr := rawControl(l.f, func(fd uintptr) {
+		err = ioctl(fd, gpioV2LineGetValuesIoctl, unsafe.Pointer(&vals))
+	})
+	if cerr != nil {
+		return 0, cerr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error: GPIO_V2_LINE_GET_VALUES_IOCTL failed: %w", err)
+	}
+
+	return int(vals.bits & 1), nil
+}
+
+func (l *gpioLine) SetValue(value int) error {
+	vals := gpioV2LineValues{bits: uint64(value & 1), mask: 1}
+
+	var err error
+	cerr := rawControl(l.f, func(fd uintptr) {
+		err = ioctl(fd, gpioV2LineSetValuesIoctl, unsafe.Pointer(&vals))
+	})
+	if cerr != nil {
+		return cerr
+	}
+	if err != nil {
+		return fmt.Errorf("error: GPIO_V2_LINE_SET_VALUES_IOCTL failed: %w", err)
+	}
+
+	return nil
+}
+
+// WaitEdge reads one struct gpio_v2_line_event. Which edge it was does
+// not matter, as Value tells the current level.
+func (l *gpioLine) WaitEdge() error {
+	_, err := io.ReadFull(l.f, make([]byte, gpioV2LineEventSize))
+
+	return err
+}
+
+func (l *gpioLine) Close() error {
+	return l.f.Close()
+}
@@ -0,0 +1,17 @@
+//go:build linux
+
+package im920
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestGPIOABISizes(t *testing.T) {
+	if s := unsafe.Sizeof(gpioV2LineRequest{}); s != 592 {
+		t.Errorf("sizeof(struct gpio_v2_line_request) => %d, want 592", s)
+	}
+	if s := unsafe.Sizeof(gpioV2LineValues{}); s != 16 {
+		t.Errorf("sizeof(struct gpio_v2_line_values) => %d, want 16", s)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package im920
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// OpenGPIOChip is only supported on Linux.
+func OpenGPIOChip(path string) (GPIOChip, error) {
+	return nil, fmt.Errorf("error: GPIO character devices are not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,179 @@
+package im920
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeLine struct {
+	m      sync.Mutex
+	value  int
+	sets   []int
+	edges  chan struct{}
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newFakeLine(value int) *fakeLine {
+	return &fakeLine{value: value, edges: make(chan struct{}, 16), closed: make(chan struct{})}
+}
+
+// drive changes the level as seen from the chip and reports the edge.
+func (l *fakeLine) drive(value int) {
+	l.m.Lock()
+	l.value = value
+	l.m.Unlock()
+
+	l.edges <- struct{}{}
+}
+
+func (l *fakeLine) Value() (int, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	return l.value, nil
+}
+
+func (l *fakeLine) SetValue(value int) error {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	l.value = value
+	l.sets = append(l.sets, value)
+
+	return nil
+}
+
+func (l *fakeLine) WaitEdge() error {
+	select {
+	case <-l.edges:
+		return nil
+	case <-l.closed:
+		return ErrClosed
+	}
+}
+
+func (l *fakeLine) Close() error {
+	l.once.Do(func() { close(l.closed) })
+
+	return nil
+}
+
+type fakeChip struct {
+	lines map[int]*fakeLine
+}
+
+func (c *fakeChip) line(offset int) (GPIOLine, error) {
+	l, ok := c.lines[offset]
+	if !ok {
+		return nil, errors.New("no such line")
+	}
+
+	return l, nil
+}
+
+func (c *fakeChip) RequestInput(offset int, consumer string) (GPIOLine, error) {
+	return c.line(offset)
+}
+
+func (c *fakeChip) RequestOutput(offset int, consumer string, value int) (GPIOLine, error) {
+	l, err := c.line(offset)
+	if err == nil {
+		l.SetValue(value)
+	}
+
+	return l, err
+}
+
+func (c *fakeChip) Close() error {
+	return nil
+}
+
+func TestGPIOBusy(t *testing.T) {
+	busy := newFakeLine(1)
+	g, err := NewGPIO(&fakeChip{lines: map[int]*fakeLine{17: busy}}, 17, -1)
+	if err != nil {
+		t.Fatalf("NewGPIO() => %v, want nil", err)
+	}
+	defer g.Close()
+
+	if !g.IsBusy() {
+		t.Errorf("IsBusy() => false, want true")
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		busy.drive(0)
+	}()
+	start := time.Now()
+	if err := g.WaitIdle(context.Background()); err != nil {
+		t.Errorf("WaitIdle() => %v, want nil", err)
+	}
+	if time.Since(start) > waitBusyInterval+20*time.Millisecond {
+		t.Errorf("WaitIdle() took %v, want it to return on the edge", time.Since(start))
+	}
+
+	busy.drive(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := g.WaitIdle(ctx); err != context.DeadlineExceeded {
+		t.Errorf("WaitIdle() while busy => %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if err := g.Reset(context.Background()); err == nil {
+		t.Errorf("Reset() without RESET line => nil, want error")
+	}
+}
+
+func TestGPIOIssueCommand(t *testing.T) {
+	busy := newFakeLine(1)
+	g, err := NewGPIO(&fakeChip{lines: map[int]*fakeLine{17: busy}}, 17, -1)
+	if err != nil {
+		t.Fatalf("NewGPIO() => %v, want nil", err)
+	}
+	defer g.Close()
+
+	serial := newFakeSerial()
+	im := New(serial, WithReadTimeout(100*time.Millisecond), WithBusyLine(g))
+	defer im.Close()
+
+	serial.setRespData([]byte("OK\r\n"))
+	if err := im.IssueCommandNormal("ENWR", ""); !errors.Is(err, ErrBusyTimeout) {
+		t.Errorf("IssueCommandNormal() while busy => %v, want %v", err, ErrBusyTimeout)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		busy.drive(0)
+	}()
+	if err := im.IssueCommandNormal("ENWR", ""); err != nil {
+		t.Errorf("IssueCommandNormal() after BUSY fell => %v, want nil", err)
+	}
+}
+
+func TestGPIOReset(t *testing.T) {
+	reset := newFakeLine(0)
+	g, err := NewGPIO(&fakeChip{lines: map[int]*fakeLine{17: newFakeLine(0), 27: reset}}, 17, 27)
+	if err != nil {
+		t.Fatalf("NewGPIO() => %v, want nil", err)
+	}
+	defer g.Close()
+
+	if err := g.Reset(context.Background()); err != nil {
+		t.Fatalf("Reset() => %v, want nil", err)
+	}
+
+	reset.m.Lock()
+	defer reset.m.Unlock()
+	want := []int{1, 0, 1}
+	if len(reset.sets) != len(want) || reset.sets[0] != 1 || reset.sets[1] != 0 || reset.sets[2] != 1 {
+		t.Errorf("Reset() => RESET driven %v, want %v", reset.sets, want)
+	}
+
+	if _, err := NewGPIO(&fakeChip{}, 17, 27); err == nil {
+		t.Errorf("NewGPIO(missing line) => nil, want error")
+	}
+}
@@ -3,11 +3,13 @@ package im920
 import (
 	"bufio"
 	"bytes"
-	"container/list"
+	"context"
 	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -20,49 +22,273 @@ type Ch uint8
 type Rssi uint8
 type Node uint8
 type Mode uint8
+type Power uint8
 
 const (
 	FAST_MODE Mode = iota + 1
 	LONG_MODE
 )
 
+// Transmit power levels accepted by STPO.
+const (
+	POWER_MINUS_10DBM Power = iota + 1 // 0.1 mW
+	POWER_0DBM                         // 1 mW
+	POWER_10DBM                        // 10 mW
+)
+
 type Config struct {
 	Name        string
+	Baud        int
+	AutoBaud    bool
 	ReadTimeout time.Duration
+	CharIO      bool
 }
 
+// ReadInfo identifies the sender of a packet. An IM920 sender is
+// identified by FromId and FromNode, an IM920sL sender by FromSLNode.
 type ReadInfo struct {
-	FromNode Node
-	FromId   Id
-	FromRssi Rssi
+	FromNode   Node
+	FromId     Id
+	FromRssi   Rssi
+	FromSLNode SLNode
 }
 
 type IM920 struct {
 	s            io.ReadWriteCloser
-	m            *sync.Mutex
+	portM        sync.Mutex
+	portGen      int
+	baud         int
+	reopen       func(baud int) (io.ReadWriteCloser, error)
+	rx           chan []byte
+	rxErr        error
+	sem          chan struct{}
 	readTimeout  time.Duration
+	infoM        sync.Mutex
 	lastReadInfo ReadInfo
-	rcvedData    *list.List
+	charIOM      sync.Mutex
+	charIO       bool
+	charIOSaved  bool // the mode the module boots in, as far as known
+	ackM         sync.Mutex
+	ack          bool
+	ackSaved     bool
+	rcvedData    chan rcvedLine
+	subM         sync.Mutex
+	subs         map[*subscription]struct{}
+	subsClosed   bool
+	resp         chan string
+	ctx          context.Context
+	cancel       context.CancelFunc
+	readerDone   chan struct{}
+	readErr      error
 	isBusyFunc   func() bool
+	busyLine     BusyLine
+	versionM     sync.Mutex
+	version      *Version
+	asleep       bool // guarded by sem
+	autoWake     bool
+	headers      func(string) (ReadInfo, error)
+	idleEOF      bool
+	lineTimeout  time.Duration
+	lineByBaud   bool
+}
+
+// BusyLine is a BUSY signal that can tell when it changes, so that
+// waiting for the module needs no polling. GPIO implements it.
+type BusyLine interface {
+	IsBusy() bool
+	// WaitIdle blocks until the line is not busy or ctx is done.
+	WaitIdle(ctx context.Context) error
 }
 
 const (
-	defaultBps       = 19200
-	maxTXDA          = 64
-	maxReadSize      = 256
-	waitBusyTimeout  = 500 * time.Millisecond
-	waitBusyInterval = 10 * time.Millisecond
+	defaultBps         = 19200
+	defaultReadTimeout = 1 * time.Second
+	maxTXDA            = 64
+	maxReadSize        = 256
+	maxRcvedData       = 64
+	maxRespLines       = 16
+	respGap            = 50 * time.Millisecond
+	lineTimeout        = 50 * time.Millisecond
+	maxRespLineLen     = 32 // an RPRM entry
+	idleInterval       = 10 * time.Millisecond
+	waitBusyTimeout    = 500 * time.Millisecond
+	waitBusyInterval   = 10 * time.Millisecond
 )
 
+type Option func(*IM920)
+
+func WithReadTimeout(d time.Duration) Option {
+	return func(im *IM920) {
+		im.readTimeout = d
+	}
+}
+
+// WithBaudRate tells New the rate rw is currently running at.
+func WithBaudRate(baud int) Option {
+	return func(im *IM920) {
+		im.baud = baud
+	}
+}
+
+// WithReopen lets SetBaudRate replace the transport with one running
+// at a different rate.
+func WithReopen(f func(baud int) (io.ReadWriteCloser, error)) Option {
+	return func(im *IM920) {
+		im.reopen = f
+	}
+}
+
+// WithCharIO tells New that the module is in character I/O mode.
+func WithCharIO(enabled bool) Option {
+	return func(im *IM920) {
+		im.charIO = enabled
+		im.charIOSaved = enabled
+	}
+}
+
+// WithIdleEOF makes io.EOF from the transport mean that no data has
+// arrived yet, as it does for a tarm/serial port with a read timeout.
+// Open sets it.
+func WithIdleEOF(enabled bool) Option {
+	return func(im *IM920) {
+		im.idleEOF = enabled
+	}
+}
+
+// WithLineTimeout sets how long a partial line may stop growing before
+// it is dropped as noise. It defaults to the read timeout, which suits
+// network transports that buffer data; Open uses a much shorter one
+// that follows the baud rate.
+func WithLineTimeout(d time.Duration) Option {
+	return func(im *IM920) {
+		im.lineTimeout = d
+		im.lineByBaud = false
+	}
+}
+
+// withLineTimeoutByBaud makes the line timeout follow the baud rate.
+func withLineTimeoutByBaud() Option {
+	return func(im *IM920) {
+		im.lineByBaud = true
+	}
+}
+
+// staleTimeout is how long a partial line may stop growing.
+func (im *IM920) staleTimeout() time.Duration {
+	if im.lineByBaud {
+		return lineTimeout + wireTime(im.BaudRate(), maxRespLineLen)
+	}
+
+	return im.lineTimeout
+}
+
+// respGapTimeout is how long getResponse waits for the next line of a
+// multi-line response: the time the longest line takes on the wire,
+// plus a margin.
+func (im *IM920) respGapTimeout() time.Duration {
+	return respGap + wireTime(im.BaudRate(), maxRespLineLen)
+}
+
+func WithBusyFunc(f func() bool) Option {
+	return func(im *IM920) {
+		im.isBusyFunc = f
+	}
+}
+
+// WithBusyLine makes commands wait for b to go idle. It takes
+// precedence over WithBusyFunc.
+func WithBusyLine(b BusyLine) Option {
+	return func(im *IM920) {
+		im.busyLine = b
+	}
+}
+
 func Open(c *Config) (*IM920, error) {
-	t := (1000 * 200 * 8 / defaultBps) * time.Millisecond
-	sc := &serial.Config{Name: c.Name, Baud: defaultBps, ReadTimeout: t}
+	return openConfig(c)
+}
+
+func openConfig(c *Config, opts ...Option) (*IM920, error) {
+	opts = append(opts, WithReadTimeout(c.ReadTimeout), WithCharIO(c.CharIO), WithIdleEOF(true), withLineTimeoutByBaud())
+
+	baud := c.Baud
+	if baud == 0 {
+		baud = defaultBps
+	}
+
+	open := func(baud int) (io.ReadWriteCloser, error) {
+		return openPort(c.Name, baud)
+	}
+
+	var im *IM920
+	if c.AutoBaud {
+		var err error
+		im, err = openAuto(open, baud, opts...)
+		if err != nil {
+			return im, err
+		}
+	} else {
+		s, err := open(baud)
+		if err != nil {
+			return &IM920{}, err
+		}
+		im = New(s, append(opts, WithBaudRate(baud), WithReopen(open))...)
+	}
+
+	im.detectModel()
+
+	return im, nil
+}
+
+// OpenAuto opens the module at whatever rate it is configured for. The
+// detected rate is reported by BaudRate.
+func OpenAuto(name string) (*IM920, error) {
+	return Open(&Config{Name: name, AutoBaud: true})
+}
+
+func openPort(name string, baud int) (io.ReadWriteCloser, error) {
+	t := time.Duration(1000*200*8/baud) * time.Millisecond
+	sc := &serial.Config{Name: name, Baud: baud, ReadTimeout: t}
 	s, err := serial.OpenPort(sc)
 	if err != nil {
-		return &IM920{}, fmt.Errorf("error: OpenPort failed: %s", err)
+		return nil, fmt.Errorf("error: OpenPort failed: %w", err)
 	}
 
-	return &IM920{s: s, m: new(sync.Mutex), readTimeout: c.ReadTimeout, rcvedData: list.New()}, nil
+	return s, nil
+}
+
+// New returns an IM920 that talks to the module over rw, which may be
+// a serial port, a pty, a network connection or a test double.
+// Reads from rw may block indefinitely; Close does not wait for a
+// pending Read to return. New takes ownership of rw and closes it on
+// Close. io.EOF from rw means the transport has gone away, unless
+// WithIdleEOF is set.
+func New(rw io.ReadWriteCloser, opts ...Option) *IM920 {
+	im := &IM920{
+		s:           rw,
+		rx:          make(chan []byte),
+		sem:         make(chan struct{}, 1),
+		baud:        defaultBps,
+		readTimeout: defaultReadTimeout,
+		rcvedData:   make(chan rcvedLine, maxRcvedData),
+		resp:        make(chan string, maxRespLines),
+		readerDone:  make(chan struct{}),
+		headers:     parseReadHeaders,
+	}
+	for _, opt := range opts {
+		opt(im)
+	}
+	if im.readTimeout <= 0 {
+		im.readTimeout = defaultReadTimeout
+	}
+	if im.lineTimeout <= 0 {
+		im.lineTimeout = im.readTimeout
+	}
+
+	im.ctx, im.cancel = context.WithCancel(context.Background())
+	go im.pump()
+	go im.reader()
+
+	return im
 }
 
 func strToUint16(s string) (val uint16, err error) {
@@ -72,7 +298,7 @@ func strToUint16(s string) (val uint16, err error) {
 
 	b, derr := hex.DecodeString(s)
 	if derr != nil {
-		err = fmt.Errorf("error: Decode failed: %s (%s)", derr, b)
+		err = fmt.Errorf("error: Decode failed: %w (%s)", derr, s)
 		return
 	}
 
@@ -98,22 +324,22 @@ func parseReadHeaders(s string) (info ReadInfo, err error) {
 	}
 
 	node, derr := hex.DecodeString(headers[0])
-	if derr != nil {
-		err = fmt.Errorf("error: Decode Node failed (%s): %s", headers[0], derr)
+	if derr != nil || len(node) != 1 {
+		err = fmt.Errorf("error: Decode Node failed (%s): %v", headers[0], derr)
 		return
 	}
 	info.FromNode = Node(node[0])
 
 	id, derr := strToUint16(headers[1])
-	if err != nil {
-		err = fmt.Errorf("error: Decode FromId failed (%s): %s", headers[1], derr)
+	if derr != nil {
+		err = fmt.Errorf("error: Decode FromId failed (%s): %w", headers[1], derr)
 		return
 	}
 	info.FromId = Id(id)
 
 	rssi, derr := hex.DecodeString(headers[2])
-	if err != nil {
-		err = fmt.Errorf("error: Decode Rssi failed (%s): %s", headers[2], derr)
+	if derr != nil || len(rssi) != 1 {
+		err = fmt.Errorf("error: Decode Rssi failed (%s): %v", headers[2], derr)
 		return
 	}
 	info.FromRssi = Rssi(rssi[0])
@@ -127,157 +353,371 @@ func (im *IM920) IsBusyFunc(f func() bool) {
 	return
 }
 
-func (im *IM920) waitNotBusy() bool {
+func (im *IM920) IsBusyLine(b BusyLine) {
+	im.busyLine = b
+
+	return
+}
+
+func (im *IM920) lock(ctx context.Context) error {
+	select {
+	case im.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (im *IM920) unlock() {
+	<-im.sem
+}
+
+func (im *IM920) waitNotBusy(ctx context.Context) error {
+	if im.busyLine != nil {
+		bctx, cancel := context.WithTimeout(ctx, waitBusyTimeout)
+		defer cancel()
+
+		err := im.busyLine.WaitIdle(bctx)
+		if err != nil && ctx.Err() == nil && bctx.Err() != nil {
+			return ErrBusyTimeout
+		}
+		return err
+	}
+
 	if im.isBusyFunc == nil {
-		return true
+		return nil
 	}
 
 	timer := time.NewTimer(waitBusyTimeout)
 	defer timer.Stop()
 
+	ticker := time.NewTicker(waitBusyInterval)
+	defer ticker.Stop()
+
 	for {
+		if !im.isBusyFunc() {
+			return nil
+		}
+
 		select {
+		case <-ctx.Done():
+			return ctx.Err()
 		case <-timer.C:
-			return false
-		default:
-			if !im.isBusyFunc() {
-				return true
-			}
-			time.Sleep(waitBusyInterval)
+			return ErrBusyTimeout
+		case <-ticker.C:
 		}
 	}
+}
 
-	return false
+func (im *IM920) port() (io.ReadWriteCloser, int) {
+	im.portM.Lock()
+	defer im.portM.Unlock()
+
+	return im.s, im.portGen
 }
 
-func (im *IM920) receive(p []byte) (readed int, err error) {
-	timer := time.NewTimer(im.readTimeout)
-	defer timer.Stop()
+func (im *IM920) BaudRate() int {
+	im.portM.Lock()
+	defer im.portM.Unlock()
+
+	return im.baud
+}
+
+// reopenPort closes the transport and opens it again at baud. The port
+// lock is held throughout so that pump waits for the new transport
+// instead of giving up on the closed one.
+func (im *IM920) reopenPort(baud int) error {
+	im.portM.Lock()
+	defer im.portM.Unlock()
+
+	im.portGen++
+	im.s.Close()
+
+	s, err := im.reopen(baud)
+	if err != nil {
+		return fmt.Errorf("error: reopen failed: %w", err)
+	}
+	im.s = s
+	im.baud = baud
+
+	return nil
+}
 
-	readedInitialbyte := false
+// pump moves bytes from the transport to rx, so that the reader never
+// blocks in the transport's Read and can be stopped by Close.
+func (im *IM920) pump() {
+	defer func() {
+		if im.rxErr == nil {
+			im.rxErr = im.ctx.Err()
+		}
+		close(im.rx)
+	}()
 
 	for {
 		select {
-		case <-timer.C:
-			if readed == 0 {
-				err = io.EOF
-			}
+		case <-im.ctx.Done():
 			return
 		default:
-			n, rerr := im.s.Read(p[readed : readed+1])
-			if rerr != nil && rerr != io.EOF {
-				err = fmt.Errorf("error: Read failed: %s", rerr)
+		}
+
+		s, gen := im.port()
+		buf := make([]byte, maxReadSize)
+		n, err := s.Read(buf)
+		if n > 0 {
+			select {
+			case im.rx <- buf[:n]:
+			case <-im.ctx.Done():
 				return
 			}
-			if n > 0 && !readedInitialbyte {
-				readedInitialbyte = true
-			}
-			if n == 0 && readedInitialbyte {
-				return
+		}
+		if err == io.EOF && im.idleEOF {
+			err = nil
+		}
+		if err != nil {
+			if _, cur := im.port(); cur != gen {
+				// the port was swapped under us; carry on with the new one
+				continue
 			}
+			im.rxErr = err
+			return
+		}
 
-			readed += n
-			if readed >= len(p) {
-				return
-			}
+		if n == 0 {
+			time.Sleep(idleInterval)
 		}
 	}
 }
 
-func (im *IM920) getResponse(p []byte) (readed int, err error) {
-	timer := time.NewTimer(im.readTimeout)
-	defer timer.Stop()
+func (im *IM920) isRcvedLine(s string) bool {
+	i := strings.Index(s, ":")
+	if i < 0 {
+		return false
+	}
+
+	_, err := im.headers(s[:i])
 
+	return err == nil
+}
+
+func pushLatest[T any](ch chan T, v T) {
 	for {
 		select {
-		case <-timer.C:
+		case ch <- v:
 			return
 		default:
-			rcv := make([]byte, maxReadSize)
-			rcved, rerr := im.receive(rcv)
-			if rerr != nil {
-				err = fmt.Errorf("error: receive failed: %s", rerr)
-				return
+			// drop the oldest entry to make room
+			select {
+			case <-ch:
+			default:
 			}
-			if rcved == 0 {
-				err = fmt.Errorf("error: receive failed: no data")
+		}
+	}
+}
+
+func (im *IM920) reader() {
+	defer close(im.readerDone)
+
+	f := NewFramer()
+
+	// a partial line that stops growing is noise or the tail of a line
+	// cut short; drop it so it cannot be glued to the next one
+	stale := time.NewTimer(im.staleTimeout())
+	defer stale.Stop()
+
+	for {
+		select {
+		case <-stale.C:
+			f.Reset()
+		case <-im.ctx.Done():
+			im.readErr = im.ctx.Err()
+			return
+		case b, ok := <-im.rx:
+			if !ok {
+				im.readErr = fmt.Errorf("error: Read failed: %w", im.rxErr)
 				return
 			}
 
-			strs := strings.Split(string(rcv[:rcved]), "\r\n")
-
-			for i, v := range strs {
-				if !strings.Contains(v, ":") {
-					resp := strings.Join(strs[i:], "\r\n")
-					readed = copy(p, resp)
+			f.Write(b)
+			for {
+				line, ok := f.Next()
+				if !ok {
 					break
 				}
+				im.dispatch(line)
+			}
 
-				if len(v) > 0 {
-					if i+1 < len(strs) {
-						v += "\r\n"
-					}
-					im.rcvedData.PushBack(v)
+			if !stale.Stop() {
+				select {
+				case <-stale.C:
+				default:
 				}
 			}
-
-			if readed > 0 {
-				return
+			if f.Buffered() > 0 {
+				stale.Reset(im.staleTimeout())
 			}
 		}
 	}
+}
 
-	return
+func (im *IM920) dispatch(line string) {
+	if im.isRcvedLine(line) {
+		at := time.Now()
+		charIO := im.isCharIO()
+		pushLatest(im.rcvedData, rcvedLine{line: line, at: at, charIO: charIO})
+		im.publish(line, at, charIO)
+	} else {
+		pushLatest(im.resp, line)
+	}
+}
+
+func (im *IM920) flushResponse() {
+	for {
+		select {
+		case <-im.resp:
+		default:
+			return
+		}
+	}
+}
+
+// multiLineCmds answer with a variable number of lines.
+var multiLineCmds = map[string]bool{
+	"RRID": true,
+	"RPRM": true,
+}
+
+// getResponse waits for the first response line. If multiLine is set,
+// it then collects further lines until none arrives for a gap that
+// depends on the baud rate. Every
+// line keeps its CRLF.
+func (im *IM920) getResponse(ctx context.Context, multiLine bool) (resp string, err error) {
+	timer := time.NewTimer(im.readTimeout)
+	defer timer.Stop()
+
+	var line string
+	select {
+	case line = <-im.resp:
+	case <-im.readerDone:
+		err = fmt.Errorf("error: reader stopped: %w", im.readErr)
+		return
+	case <-ctx.Done():
+		err = ctx.Err()
+		return
+	case <-timer.C:
+		err = ErrNoResponse
+		return
+	}
+	resp = line + "\r\n"
+	if !multiLine {
+		return
+	}
+
+	gapTimeout := im.respGapTimeout()
+	gap := time.NewTimer(gapTimeout)
+	defer gap.Stop()
+
+	for {
+		select {
+		case line = <-im.resp:
+			resp += line + "\r\n"
+			if !gap.Stop() {
+				<-gap.C
+			}
+			gap.Reset(gapTimeout)
+		case <-gap.C:
+			return
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		}
+	}
 }
 
 func (im *IM920) IssueCommand(cmd, param string) (resp []byte, err error) {
-	im.m.Lock()
-	defer im.m.Unlock()
+	return im.IssueCommandContext(context.Background(), cmd, param)
+}
 
-	if !im.waitNotBusy() {
-		err = fmt.Errorf("error: BusyWait failed")
+func (im *IM920) IssueCommandContext(ctx context.Context, cmd, param string) (resp []byte, err error) {
+	if lerr := im.lock(ctx); lerr != nil {
+		err = &CommandError{Cmd: cmd, Param: param, Err: lerr}
 		return
 	}
+	defer im.unlock()
 
-	_, werr := im.s.Write([]byte(cmd + " " + param + "\r\n"))
-	if werr != nil {
-		err = fmt.Errorf("error: Write failed: %s", werr)
+	if serr := im.awake(ctx); serr != nil {
+		err = &CommandError{Cmd: cmd, Param: param, Err: serr}
 		return
 	}
 
-	rcv := make([]byte, maxReadSize)
-	rcved, rerr := im.getResponse(rcv)
-	if rerr != nil {
-		err = fmt.Errorf("error: getResponse failed: %s", rerr)
+	return im.issue(ctx, cmd, param)
+}
+
+// issue sends a command and waits for its response. The caller must
+// hold the command lock.
+func (im *IM920) issue(ctx context.Context, cmd, param string) (resp []byte, err error) {
+	if berr := im.waitNotBusy(ctx); berr != nil {
+		err = &CommandError{Cmd: cmd, Param: param, Err: berr}
+		return
+	}
+
+	// a response to an earlier, abandoned command must not be taken
+	// for the response to this one
+	im.flushResponse()
+
+	s, _ := im.port()
+	_, werr := s.Write([]byte(cmd + " " + param + "\r\n"))
+	if werr != nil {
+		err = &CommandError{Cmd: cmd, Param: param, Err: fmt.Errorf("error: Write failed: %w", werr)}
 		return
 	}
-	if rcved == 0 {
-		err = fmt.Errorf("error: getResponse failed: no data")
+
+	rcv, rerr := im.getResponse(ctx, multiLineCmds[cmd])
+	if rerr != nil {
+		err = &CommandError{Cmd: cmd, Param: param, Err: rerr}
 		return
 	}
 
-	if bytes.Equal(rcv[:rcved], []byte("NG\r\n")) {
-		err = fmt.Errorf("NG response")
+	if rcv == "NG\r\n" {
+		err = &CommandError{Cmd: cmd, Param: param, Raw: []byte(rcv), Err: ErrNG}
 	}
 
-	return rcv[:rcved], err
+	return []byte(rcv), err
 }
 
 func (im *IM920) IssueCommandNormal(cmd, param string) error {
-	resp, err := im.IssueCommand(cmd, param)
+	return im.IssueCommandNormalContext(context.Background(), cmd, param)
+}
+
+func (im *IM920) IssueCommandNormalContext(ctx context.Context, cmd, param string) error {
+	resp, err := im.IssueCommandContext(ctx, cmd, param)
+
+	return checkOK(cmd, param, resp, err)
+}
+
+func (im *IM920) issueOK(ctx context.Context, cmd, param string) error {
+	resp, err := im.issue(ctx, cmd, param)
+
+	return checkOK(cmd, param, resp, err)
+}
+
+func checkOK(cmd, param string, resp []byte, err error) error {
 	if err != nil {
 		return err
 	}
 
 	if !bytes.Equal(resp, []byte("OK\r\n")) {
-		return fmt.Errorf("error: Unknown response (%v)", resp)
+		return &CommandError{Cmd: cmd, Param: param, Raw: resp, Err: ErrUnexpectedResponse}
 	}
 
 	return nil
 }
 
 func (im *IM920) IssueCommandRespStr(cmd, param string) (resp string, err error) {
-	rcv, err := im.IssueCommand(cmd, param)
+	return im.IssueCommandRespStrContext(context.Background(), cmd, param)
+}
+
+func (im *IM920) IssueCommandRespStrContext(ctx context.Context, cmd, param string) (resp string, err error) {
+	rcv, err := im.IssueCommandContext(ctx, cmd, param)
 	if err != nil {
 		resp = strings.Replace(string(rcv), "\r\n", "", -1)
 		return
@@ -285,7 +725,7 @@ func (im *IM920) IssueCommandRespStr(cmd, param string) (resp string, err error)
 
 	dataEnd := strings.LastIndex(string(rcv), "\r\n")
 	if strings.Index(string(rcv), "\r\n") < 0 {
-		err = fmt.Errorf("error: not found the end of data (%v)", rcv)
+		err = &CommandError{Cmd: cmd, Param: param, Raw: rcv, Err: ErrUnexpectedResponse}
 		return
 	}
 
@@ -295,18 +735,29 @@ func (im *IM920) IssueCommandRespStr(cmd, param string) (resp string, err error)
 }
 
 func (im *IM920) IssueCommandRespNum(cmd, param string) (resp uint16, err error) {
-	rcv, err := im.IssueCommandRespStr(cmd, param)
+	return im.IssueCommandRespNumContext(context.Background(), cmd, param)
+}
+
+func (im *IM920) IssueCommandRespNumContext(ctx context.Context, cmd, param string) (resp uint16, err error) {
+	rcv, err := im.IssueCommandRespStrContext(ctx, cmd, param)
 	if err != nil {
 		return
 	}
 
-	resp, err = strToUint16(rcv)
+	resp, derr := strToUint16(rcv)
+	if derr != nil {
+		err = &CommandError{Cmd: cmd, Param: param, Raw: []byte(rcv), Err: fmt.Errorf("%w: %s", ErrUnexpectedResponse, derr)}
+	}
 
 	return
 }
 
 func (im *IM920) IssueCommandRespNums(cmd, param string) (resp []uint16, err error) {
-	rcv, err := im.IssueCommandRespStr(cmd, param)
+	return im.IssueCommandRespNumsContext(context.Background(), cmd, param)
+}
+
+func (im *IM920) IssueCommandRespNumsContext(ctx context.Context, cmd, param string) (resp []uint16, err error) {
+	rcv, err := im.IssueCommandRespStrContext(ctx, cmd, param)
 	if err != nil {
 		return
 	}
@@ -316,7 +767,7 @@ func (im *IM920) IssueCommandRespNums(cmd, param string) (resp []uint16, err err
 	for scanner.Scan() {
 		val, terr := strToUint16(scanner.Text())
 		if terr != nil {
-			err = terr
+			err = &CommandError{Cmd: cmd, Param: param, Raw: []byte(rcv), Err: fmt.Errorf("%w: %s", ErrUnexpectedResponse, terr)}
 			return
 		}
 		resp = append(resp, val)
@@ -325,15 +776,51 @@ func (im *IM920) IssueCommandRespNums(cmd, param string) (resp []uint16, err err
 	return
 }
 
+// writeEnable runs f inside an ENWR/DSWR window when persist is set.
+// DSWR is issued even if f fails or ctx is cancelled, so the module is
+// never left write-enabled.
+func (im *IM920) writeEnable(ctx context.Context, persist bool, f func() error) (err error) {
+	if !persist {
+		return f()
+	}
+
+	ierr := im.IssueCommandNormalContext(ctx, "ENWR", "")
+	if ierr != nil {
+		return fmt.Errorf("error: ENWR failed: %w", ierr)
+	}
+
+	err = f()
+
+	ierr = im.IssueCommandNormal("DSWR", "")
+	if ierr != nil && err == nil {
+		err = fmt.Errorf("error: DSWR failed: %w", ierr)
+	}
+
+	return
+}
+
+// Write sends at most 64 bytes of p in a single TXDA and returns the
+// number of bytes sent. Use a Messenger for larger payloads.
 func (im *IM920) Write(p []byte) (n int, err error) {
+	return im.WriteContext(context.Background(), p)
+}
+
+func (im *IM920) WriteContext(ctx context.Context, p []byte) (n int, err error) {
 	cmd := "TXDA"
 	b2w := len(p)
 	if len(p) > maxTXDA {
 		b2w = maxTXDA
 	}
-	param := strings.ToUpper(hex.EncodeToString(p[:b2w]))
 
-	err = im.IssueCommandNormal(cmd, param)
+	param, err := im.encodePayload(p[:b2w])
+	if err != nil {
+		return 0, err
+	}
+
+	err = im.IssueCommandNormalContext(ctx, cmd, param)
+	if errors.Is(err, ErrNG) && im.GetAckMode() {
+		err = fmt.Errorf("%w: %w", ErrNotAcknowledged, err)
+	}
 	if err != nil {
 		n = 0
 	} else {
@@ -343,65 +830,68 @@ func (im *IM920) Write(p []byte) (n int, err error) {
 	return
 }
 
-func (im *IM920) Read(p []byte) (n int, err error) {
-	im.m.Lock()
-	defer im.m.Unlock()
-
-	str := ""
-	if im.rcvedData.Len() > 0 {
-		e := im.rcvedData.Front()
-		str = e.Value.(string)
-		im.rcvedData.Remove(e)
-	} else {
-		buf := make([]byte, maxReadSize)
-		readed, err := im.receive(buf)
-		if err != nil && err != io.EOF {
-			return 0, fmt.Errorf("error: Read failed: %s", err)
-		}
-		if readed == 0 {
-			return 0, io.EOF
-		}
-		str = string(buf)
+// encodePayload formats p as a TXDA parameter: hex, or the text itself
+// in character I/O mode.
+func (im *IM920) encodePayload(p []byte) (string, error) {
+	if !im.isCharIO() {
+		return strings.ToUpper(hex.EncodeToString(p)), nil
 	}
 
-	strs := strings.Split(str, ":")
-	if len(strs) < 2 {
-		return 0, fmt.Errorf("error: Split header and data failed (%s)", str)
+	for _, c := range p {
+		if c < 0x20 || c > 0x7e {
+			return "", fmt.Errorf("error: 0x%02X cannot be sent in character I/O mode", c)
+		}
 	}
 
-	info, err := parseReadHeaders(strs[0])
-	if err != nil {
-		return 0, fmt.Errorf("error: parseReadHeaders failed: %s", err)
-	}
+	return string(p), nil
+}
+
+func (im *IM920) Read(p []byte) (n int, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), im.readTimeout)
+	defer cancel()
 
-	dataEnd := strings.Index(strs[1], "\r\n")
-	if dataEnd < 0 {
-		return 0, fmt.Errorf("error: not found the end of data (%s)", strs[1])
+	n, err = im.ReadContext(ctx, p)
+	if err == context.DeadlineExceeded {
+		err = io.EOF
 	}
 
-	dataStr := strings.Replace(strs[1][:dataEnd], ",", "", -1)
-	data, err := hex.DecodeString(dataStr)
+	return
+}
+
+// ReadContext waits for received data until ctx is done, regardless of
+// Config.ReadTimeout.
+func (im *IM920) ReadContext(ctx context.Context, p []byte) (n int, err error) {
+	pkt, err := im.ReadPacketContext(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("error: Decode failed (%s): %s", dataStr, err)
-	}
-	if len(data) == 0 {
-		return 0, fmt.Errorf("error: Decode failed: no data")
+		return 0, err
 	}
 
-	n = copy(p, data)
-	im.lastReadInfo = info
+	n = copy(p, pkt.Data)
+
+	im.infoM.Lock()
+	im.lastReadInfo = pkt.ReadInfo
+	im.infoM.Unlock()
 
 	return
 }
 
+// LastReadInfo returns the sender of the data last returned by Read.
+// Use ReadPacket instead when more than one goroutine reads.
 func (im *IM920) LastReadInfo() ReadInfo {
+	im.infoM.Lock()
+	defer im.infoM.Unlock()
+
 	return im.lastReadInfo
 }
 
 func (im *IM920) GetId() (id Id, err error) {
-	rcv, ierr := im.IssueCommandRespNum("RDID", "")
+	return im.GetIdContext(context.Background())
+}
+
+func (im *IM920) GetIdContext(ctx context.Context) (id Id, err error) {
+	rcv, ierr := im.IssueCommandRespNumContext(ctx, "RDID", "")
 	if ierr != nil {
-		err = fmt.Errorf("error: RDID failed: %s", ierr)
+		err = fmt.Errorf("error: RDID failed: %w", ierr)
 		return
 	}
 
@@ -411,33 +901,34 @@ func (im *IM920) GetId() (id Id, err error) {
 }
 
 func (im *IM920) AddRcvId(id Id) (err error) {
-	ierr := im.IssueCommandNormal("ENWR", "")
-	if ierr != nil {
-		err = fmt.Errorf("error: ENWR failed: %s", ierr)
-		return
-	}
+	return im.AddRcvIdContext(context.Background(), id)
+}
+
+func (im *IM920) AddRcvIdContext(ctx context.Context, id Id) (err error) {
+	return im.writeEnable(ctx, true, func() error {
+		return im.addRcvId(ctx, id)
+	})
+}
 
+func (im *IM920) addRcvId(ctx context.Context, id Id) error {
 	b := make([]byte, 2)
 	binary.BigEndian.PutUint16(b, uint16(id))
-	ierr = im.IssueCommandNormal("SRID", hex.EncodeToString(b))
+	ierr := im.IssueCommandNormalContext(ctx, "SRID", hex.EncodeToString(b))
 	if ierr != nil {
-		err = fmt.Errorf("error: SRID failed: %s", ierr)
-		return
+		return fmt.Errorf("error: SRID failed: %w", ierr)
 	}
 
-	ierr = im.IssueCommandNormal("DSWR", "")
-	if ierr != nil {
-		err = fmt.Errorf("error: DSWR failed: %s", ierr)
-		return
-	}
-
-	return
+	return nil
 }
 
 func (im *IM920) GetAllRcvId() (ids []Id, err error) {
-	rcv, ierr := im.IssueCommandRespNums("RRID", "")
+	return im.GetAllRcvIdContext(context.Background())
+}
+
+func (im *IM920) GetAllRcvIdContext(ctx context.Context) (ids []Id, err error) {
+	rcv, ierr := im.IssueCommandRespNumsContext(ctx, "RRID", "")
 	if ierr != nil {
-		err = fmt.Errorf("error: RRID failed: %s", ierr)
+		err = fmt.Errorf("error: RRID failed: %w", ierr)
 		return
 	}
 
@@ -449,68 +940,105 @@ func (im *IM920) GetAllRcvId() (ids []Id, err error) {
 }
 
 func (im *IM920) DeleteAllRcvId() error {
-	ierr := im.IssueCommandNormal("ENWR", "")
-	if ierr != nil {
-		return fmt.Errorf("error: ENWR failed: %s", ierr)
-	}
+	return im.DeleteAllRcvIdContext(context.Background())
+}
 
-	ierr = im.IssueCommandNormal("ERID", "")
-	if ierr != nil {
-		return fmt.Errorf("error: ERID failed: %s", ierr)
-	}
+func (im *IM920) DeleteAllRcvIdContext(ctx context.Context) error {
+	return im.writeEnable(ctx, true, func() error {
+		return im.deleteAllRcvId(ctx)
+	})
+}
 
-	ierr = im.IssueCommandNormal("DSWR", "")
+func (im *IM920) deleteAllRcvId(ctx context.Context) error {
+	ierr := im.IssueCommandNormalContext(ctx, "ERID", "")
 	if ierr != nil {
-		return fmt.Errorf("error: DSWR failed: %s", ierr)
+		return fmt.Errorf("error: ERID failed: %w", ierr)
 	}
 
 	return nil
 }
 
 func (im *IM920) SetCh(ch Ch, persist bool) (err error) {
-	if persist {
-		ierr := im.IssueCommandNormal("ENWR", "")
-		if ierr != nil {
-			err = fmt.Errorf("error: ENWR failed: %s", ierr)
-			return
-		}
-	}
+	return im.SetChContext(context.Background(), ch, persist)
+}
 
+func (im *IM920) SetChContext(ctx context.Context, ch Ch, persist bool) (err error) {
+	return im.writeEnable(ctx, persist, func() error {
+		return im.setCh(ctx, ch)
+	})
+}
+
+func (im *IM920) setCh(ctx context.Context, ch Ch) error {
 	b := make([]byte, 1)
 	b[0] = byte(ch)
-	ierr := im.IssueCommandNormal("STCH", hex.EncodeToString(b))
+	ierr := im.IssueCommandNormalContext(ctx, "STCH", hex.EncodeToString(b))
 	if ierr != nil {
-		err = fmt.Errorf("error: STCH failed: %s", ierr)
-		return
+		return fmt.Errorf("error: STCH failed: %w", ierr)
 	}
 
-	if persist {
-		ierr := im.IssueCommandNormal("DSWR", "")
-		if ierr != nil {
-			err = fmt.Errorf("error: DSWR failed: %s", ierr)
-			return
-		}
+	return nil
+}
+
+func (im *IM920) GetCh() (ch Ch, err error) {
+	return im.GetChContext(context.Background())
+}
+
+func (im *IM920) GetChContext(ctx context.Context) (ch Ch, err error) {
+	rcv, ierr := im.IssueCommandRespNumContext(ctx, "RDCH", "")
+	if ierr != nil {
+		err = fmt.Errorf("error: RDCH failed: %w", ierr)
+		return
 	}
 
+	ch = Ch(rcv)
+
 	return
 }
 
-func (im *IM920) GetCh() (ch Ch, err error) {
-	rcv, ierr := im.IssueCommandRespNum("RDCH", "")
+// SetNode sets the node number the module prefixes to what it sends.
+func (im *IM920) SetNode(node Node, persist bool) (err error) {
+	return im.SetNodeContext(context.Background(), node, persist)
+}
+
+func (im *IM920) SetNodeContext(ctx context.Context, node Node, persist bool) (err error) {
+	return im.writeEnable(ctx, persist, func() error {
+		return im.setNode(ctx, node)
+	})
+}
+
+func (im *IM920) setNode(ctx context.Context, node Node) error {
+	ierr := im.IssueCommandNormalContext(ctx, "STNN", hex.EncodeToString([]byte{byte(node)}))
 	if ierr != nil {
-		err = fmt.Errorf("error: RDCH failed: %s", ierr)
+		return fmt.Errorf("error: STNN failed: %w", ierr)
+	}
+
+	return nil
+}
+
+func (im *IM920) GetNode() (node Node, err error) {
+	return im.GetNodeContext(context.Background())
+}
+
+func (im *IM920) GetNodeContext(ctx context.Context) (node Node, err error) {
+	rcv, ierr := im.IssueCommandRespNumContext(ctx, "RDNN", "")
+	if ierr != nil {
+		err = fmt.Errorf("error: RDNN failed: %w", ierr)
 		return
 	}
 
-	ch = Ch(rcv)
+	node = Node(rcv)
 
 	return
 }
 
 func (im *IM920) GetRssi() (rssi Rssi, err error) {
-	rcv, ierr := im.IssueCommandRespNum("RDRS", "")
+	return im.GetRssiContext(context.Background())
+}
+
+func (im *IM920) GetRssiContext(ctx context.Context) (rssi Rssi, err error) {
+	rcv, ierr := im.IssueCommandRespNumContext(ctx, "RDRS", "")
 	if ierr != nil {
-		err = fmt.Errorf("error: RDRS failed: %s", ierr)
+		err = fmt.Errorf("error: RDRS failed: %w", ierr)
 		return
 	}
 
@@ -520,45 +1048,160 @@ func (im *IM920) GetRssi() (rssi Rssi, err error) {
 }
 
 func (im *IM920) SetCommMode(mode Mode, persist bool) (err error) {
-	if persist {
-		ierr := im.IssueCommandNormal("ENWR", "")
-		if ierr != nil {
-			err = fmt.Errorf("error: ENWR failed: %s", ierr)
-			return
-		}
-	}
+	return im.SetCommModeContext(context.Background(), mode, persist)
+}
 
+func (im *IM920) SetCommModeContext(ctx context.Context, mode Mode, persist bool) (err error) {
+	return im.writeEnable(ctx, persist, func() error {
+		return im.setCommMode(ctx, mode)
+	})
+}
+
+func (im *IM920) setCommMode(ctx context.Context, mode Mode) error {
 	b := make([]byte, 1)
 	b[0] = byte(mode)
-	ierr := im.IssueCommandNormal("STRT", hex.EncodeToString(b))
+	ierr := im.IssueCommandNormalContext(ctx, "STRT", hex.EncodeToString(b))
+	if ierr != nil {
+		return fmt.Errorf("error: STRT failed: %w", ierr)
+	}
+
+	return nil
+}
+
+func (im *IM920) GetCommMode() (mode Mode, err error) {
+	return im.GetCommModeContext(context.Background())
+}
+
+func (im *IM920) GetCommModeContext(ctx context.Context) (mode Mode, err error) {
+	rcv, ierr := im.IssueCommandRespNumContext(ctx, "RDRT", "")
 	if ierr != nil {
-		err = fmt.Errorf("error: STRT failed: %s", ierr)
+		err = fmt.Errorf("error: RDRT failed: %w", ierr)
 		return
 	}
 
-	if persist {
-		ierr := im.IssueCommandNormal("DSWR", "")
-		if ierr != nil {
-			err = fmt.Errorf("error: DSWR failed: %s", ierr)
+	mode = Mode(rcv)
+
+	return
+}
+
+// SetCharIOMode switches character I/O mode (ECIO) on or off (DCIO).
+// In character I/O mode payloads are printable ASCII text instead of
+// hex, so Write rejects any other byte; Messenger and streams need it
+// off.
+func (im *IM920) SetCharIOMode(enabled, persist bool) (err error) {
+	return im.SetCharIOModeContext(context.Background(), enabled, persist)
+}
+
+func (im *IM920) SetCharIOModeContext(ctx context.Context, enabled, persist bool) (err error) {
+	if enabled {
+		if err = im.require(CAP_CHAR_IO); err != nil {
 			return
 		}
 	}
 
-	return
+	return im.writeEnable(ctx, persist, func() error {
+		if err := im.setCharIOMode(ctx, enabled); err != nil {
+			return err
+		}
+		if persist {
+			im.saveCharIO(enabled)
+		}
+
+		return nil
+	})
 }
 
-func (im *IM920) GetCommMode() (mode Mode, err error) {
-	rcv, ierr := im.IssueCommandRespNum("RDRT", "")
+func (im *IM920) setCharIOMode(ctx context.Context, enabled bool) error {
+	cmd := "DCIO"
+	if enabled {
+		cmd = "ECIO"
+	}
+
+	ierr := im.IssueCommandNormalContext(ctx, cmd, "")
+	if ierr != nil {
+		return fmt.Errorf("error: %s failed: %w", cmd, ierr)
+	}
+
+	im.setCharIO(enabled)
+
+	return nil
+}
+
+// GetCharIOMode reports whether the driver is in character I/O mode:
+// what SetCharIOMode or WithCharIO last set, or what the module last
+// reported to ReadSettings. Only RPRM reports the mode, so on firmware
+// without RPRM the driver cannot ask.
+func (im *IM920) GetCharIOMode() bool {
+	return im.isCharIO()
+}
+
+func (im *IM920) setCharIO(enabled bool) {
+	im.charIOM.Lock()
+	defer im.charIOM.Unlock()
+
+	im.charIO = enabled
+}
+
+func (im *IM920) saveCharIO(enabled bool) {
+	im.charIOM.Lock()
+	defer im.charIOM.Unlock()
+
+	im.charIOSaved = enabled
+}
+
+func (im *IM920) isCharIO() bool {
+	im.charIOM.Lock()
+	defer im.charIOM.Unlock()
+
+	return im.charIO
+}
+
+func (im *IM920) SetTxPower(power Power, persist bool) (err error) {
+	return im.SetTxPowerContext(context.Background(), power, persist)
+}
+
+func (im *IM920) SetTxPowerContext(ctx context.Context, power Power, persist bool) (err error) {
+	if power < POWER_MINUS_10DBM || power > POWER_10DBM {
+		return fmt.Errorf("error: invalid power level (%d)", power)
+	}
+
+	return im.writeEnable(ctx, persist, func() error {
+		return im.setTxPower(ctx, power)
+	})
+}
+
+func (im *IM920) setTxPower(ctx context.Context, power Power) error {
+	ierr := im.IssueCommandNormalContext(ctx, "STPO", strconv.Itoa(int(power)))
 	if ierr != nil {
-		err = fmt.Errorf("error: RDRT failed: %s", ierr)
+		return fmt.Errorf("error: STPO failed: %w", ierr)
+	}
+
+	return nil
+}
+
+func (im *IM920) GetTxPower() (power Power, err error) {
+	return im.GetTxPowerContext(context.Background())
+}
+
+func (im *IM920) GetTxPowerContext(ctx context.Context) (power Power, err error) {
+	rcv, ierr := im.IssueCommandRespNumContext(ctx, "RDPO", "")
+	if ierr != nil {
+		err = fmt.Errorf("error: RDPO failed: %w", ierr)
 		return
 	}
 
-	mode = Mode(rcv)
+	power = Power(rcv)
 
 	return
 }
 
 func (im *IM920) Close() error {
-	return im.s.Close()
+	im.cancel()
+	s, _ := im.port()
+	err := s.Close()
+
+	<-im.readerDone
+	im.closeSubscriptions()
+
+	return err
 }
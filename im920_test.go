@@ -2,7 +2,10 @@ package im920
 
 import (
 	"bytes"
-	"container/list"
+	"context"
+	"errors"
+	"io"
+	"net"
 	"reflect"
 	"sync"
 	"testing"
@@ -10,16 +13,64 @@ import (
 )
 
 type fakeSerial struct {
+	m          sync.Mutex
 	dummyData  []byte
+	respData   [][]byte
 	writedData []byte
+	writedAll  [][]byte
 }
 
 func newFakeSerial() *fakeSerial {
 	return &fakeSerial{}
 }
 
+func newTestIM920(serial *fakeSerial) *IM920 {
+	return New(serial, WithReadTimeout(100*time.Millisecond))
+}
+
+// setDummyData makes p readable immediately.
+func (serial *fakeSerial) setDummyData(p []byte) {
+	serial.m.Lock()
+	defer serial.m.Unlock()
+
+	serial.dummyData = p
+}
+
+// setRespData makes each p readable after the corresponding Write,
+// like a module answering a sequence of commands.
+func (serial *fakeSerial) setRespData(p ...[]byte) {
+	serial.m.Lock()
+	defer serial.m.Unlock()
+
+	serial.respData = p
+}
+
+func (serial *fakeSerial) getWritedData() []byte {
+	serial.m.Lock()
+	defer serial.m.Unlock()
+
+	return serial.writedData
+}
+
+// takeWritedAll returns every write since the last call.
+func (serial *fakeSerial) takeWritedAll() [][]byte {
+	serial.m.Lock()
+	defer serial.m.Unlock()
+
+	all := serial.writedAll
+	serial.writedAll = nil
+	return all
+}
+
 func (serial *fakeSerial) Read(p []byte) (n int, err error) {
+	serial.m.Lock()
+	defer serial.m.Unlock()
+
 	if len(serial.dummyData) == 0 {
+		// give other goroutines a chance, as a real port would block
+		serial.m.Unlock()
+		time.Sleep(time.Millisecond)
+		serial.m.Lock()
 		return 0, nil
 	}
 
@@ -40,56 +91,16 @@ func (serial *fakeSerial) Close() error {
 }
 
 func (serial *fakeSerial) Write(p []byte) (n int, err error) {
-	serial.writedData = p
-	return len(p), nil
-}
-
-var ReceiveTests = []struct {
-	in_out         []byte
-	out_errorIsNil bool
-}{
-	{
-		[]byte("00,06E5,B5:0A,1F,76,00,00,00,00,00\r\n"), true,
-	},
-	{
-		[]byte("00,06E5,B5:0A,1F,76\r\n"), true,
-	},
-	{
-		[]byte("00,06E5,B5:0A\r\n"), true,
-	},
-	{
-		[]byte("00,06E5,B5:0A,1F,76"), true,
-	},
-	{
-		[]byte("00,06E5,B5:\r\n"), true,
-	},
-	{
-		[]byte("00,06E5,B5\r\n"), true,
-	},
-	{
-		[]byte(""), false,
-	},
-}
-
-func TestReceive(t *testing.T) {
-	serial := newFakeSerial()
-	im := &IM920{s: serial, m: new(sync.Mutex), readTimeout: 100 * time.Millisecond, rcvedData: list.New()}
+	serial.m.Lock()
+	defer serial.m.Unlock()
 
-	buf := make([]byte, maxReadSize)
-
-	for i, tt := range ReceiveTests {
-		serial.dummyData = tt.in_out
-		n, err := im.receive(buf)
-		if (tt.out_errorIsNil && (err != nil)) ||
-			(!tt.out_errorIsNil && (err == nil)) {
-			t.Errorf("[%d]receive() => %v, want errorIsNil = %v",
-				i, err, tt.out_errorIsNil)
-		}
-		if !bytes.Equal(buf[:n], tt.in_out) {
-			t.Errorf("[%d]receive() => %v, want data = %v",
-				i, buf, tt.in_out)
-		}
+	serial.writedData = p
+	serial.writedAll = append(serial.writedAll, p)
+	if len(serial.respData) > 0 {
+		serial.dummyData = append(serial.dummyData, serial.respData[0]...)
+		serial.respData = serial.respData[1:]
 	}
+	return len(p), nil
 }
 
 var IssueCommandTests = []struct {
@@ -122,19 +133,27 @@ var IssueCommandTests = []struct {
 	},
 	{
 		"HOGE", "HUGA", []byte("00,06E5,B5:0A\r\nOK\r\n"),
-		[]byte("OK\r\n"), []string{"00,06E5,B5:0A\r\n"}, true,
+		[]byte("OK\r\n"), []string{"00,06E5,B5:0A"}, true,
 	},
 	{
 		"HOGE", "HUGA", []byte("00,06E5,B5:0A\r\n00,06E5,B5:0B\r\nOK\r\n"),
-		[]byte("OK\r\n"), []string{"00,06E5,B5:0A\r\n", "00,06E5,B5:0B\r\n"}, true,
+		[]byte("OK\r\n"), []string{"00,06E5,B5:0A", "00,06E5,B5:0B"}, true,
 	},
 	{
 		"HOGE", "HUGA", []byte("00,06E5,B5:0A\r\n00,06E5,B5:0B\r\nOK"),
-		[]byte("OK"), []string{"00,06E5,B5:0A\r\n", "00,06E5,B5:0B\r\n"}, true,
+		[]byte(""), []string{"00,06E5,B5:0A", "00,06E5,B5:0B"}, false,
 	},
 	{
 		"HOGE", "HUGA", []byte("00,06E5,B5:0B\r\nNG\r\n"),
-		[]byte("NG\r\n"), []string{"00,06E5,B5:0B\r\n"}, false,
+		[]byte("NG\r\n"), []string{"00,06E5,B5:0B"}, false,
+	},
+	{
+		"RRID", "", []byte("0000\r\n00,06E5,B5:0B\r\n0001\r\n"),
+		[]byte("0000\r\n0001\r\n"), []string{"00,06E5,B5:0B"}, true,
+	},
+	{
+		"HOGE", "HUGA", []byte("\x8f\xf0OK\r\n"),
+		[]byte("OK\r\n"), nil, true,
 	},
 }
 
@@ -142,8 +161,8 @@ func TestIssueCommand(t *testing.T) {
 	serial := newFakeSerial()
 
 	for i, tt := range IssueCommandTests {
-		im := &IM920{s: serial, m: new(sync.Mutex), readTimeout: 100 * time.Millisecond, rcvedData: list.New()}
-		serial.dummyData = tt.in_dummyData
+		im := newTestIM920(serial)
+		serial.setRespData(tt.in_dummyData)
 		resp, err := im.IssueCommand(tt.in_cmd, tt.in_param)
 		if (tt.out_errorIsNil && (err != nil)) ||
 			(!tt.out_errorIsNil && (err == nil)) {
@@ -154,18 +173,18 @@ func TestIssueCommand(t *testing.T) {
 			t.Errorf("[%d]IssueCommand() => %v, want data = %v",
 				i, resp, tt.out)
 		}
-		if im.rcvedData.Len() != len(tt.out_rcvedData) {
+		if len(im.rcvedData) != len(tt.out_rcvedData) {
 			t.Errorf("[%d]IssueCommand() => %v, want len = %v",
-				i, im.rcvedData.Len(), len(tt.out_rcvedData))
+				i, len(im.rcvedData), len(tt.out_rcvedData))
 		}
-		j := 0
-		for e := im.rcvedData.Front(); e != nil; e = e.Next() {
-			if e.Value != tt.out_rcvedData[j] {
+		for j := 0; len(im.rcvedData) > 0; j++ {
+			v := (<-im.rcvedData).line
+			if j < len(tt.out_rcvedData) && v != tt.out_rcvedData[j] {
 				t.Errorf("[%d]IssueCommand() => %v, want out_rcvedData = %v",
-					i, e, tt.out_rcvedData[j])
+					i, v, tt.out_rcvedData[j])
 			}
-			j++
 		}
+		im.Close()
 	}
 }
 
@@ -199,10 +218,11 @@ var IssueCommandNormalTests = []struct {
 
 func TestIssueNormalCommand(t *testing.T) {
 	serial := newFakeSerial()
-	im := &IM920{s: serial, m: new(sync.Mutex), readTimeout: 100 * time.Millisecond, rcvedData: list.New()}
+	im := newTestIM920(serial)
+	defer im.Close()
 
 	for i, tt := range IssueCommandNormalTests {
-		serial.dummyData = tt.in_dummyData
+		serial.setRespData(tt.in_dummyData)
 		err := im.IssueCommandNormal(tt.in_cmd, tt.in_param)
 		if (tt.out_errorIsNil && (err != nil)) ||
 			(!tt.out_errorIsNil && (err == nil)) {
@@ -212,6 +232,23 @@ func TestIssueNormalCommand(t *testing.T) {
 	}
 }
 
+func TestIssueCommandSingleLine(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		serial.setRespData([]byte("OK\r\n"))
+		if err := im.IssueCommandNormal("TXDA", "01"); err != nil {
+			t.Fatalf("[%d]IssueCommandNormal() => %v, want nil", i, err)
+		}
+	}
+	if d := time.Since(start); d >= 10*respGap {
+		t.Errorf("10 single line commands took %v, want less than %v", d, 10*respGap)
+	}
+}
+
 var IssueCommandRespStrTests = []struct {
 	in_cmd         string
 	in_param       string
@@ -232,7 +269,7 @@ var IssueCommandRespStrTests = []struct {
 		"FUGA", true,
 	},
 	{
-		"HOGE", "HUGA", []byte("FUGA\r\nFUGA\r\n"),
+		"RRID", "", []byte("FUGA\r\nFUGA\r\n"),
 		"FUGA\r\nFUGA", true,
 	},
 	{
@@ -247,10 +284,11 @@ var IssueCommandRespStrTests = []struct {
 
 func TestIssueCommandRespStr(t *testing.T) {
 	serial := newFakeSerial()
-	im := &IM920{s: serial, m: new(sync.Mutex), readTimeout: 100 * time.Millisecond, rcvedData: list.New()}
+	im := newTestIM920(serial)
+	defer im.Close()
 
 	for i, tt := range IssueCommandRespStrTests {
-		serial.dummyData = tt.in_dummyData
+		serial.setRespData(tt.in_dummyData)
 		resp, err := im.IssueCommandRespStr(tt.in_cmd, tt.in_param)
 		if (tt.out_errorIsNil && (err != nil)) ||
 			(!tt.out_errorIsNil && (err == nil)) {
@@ -311,10 +349,11 @@ var IssueCommandRespNumTests = []struct {
 
 func TestIssueCommandRespNum(t *testing.T) {
 	serial := newFakeSerial()
-	im := &IM920{s: serial, m: new(sync.Mutex), readTimeout: 100 * time.Millisecond, rcvedData: list.New()}
+	im := newTestIM920(serial)
+	defer im.Close()
 
 	for i, tt := range IssueCommandRespNumTests {
-		serial.dummyData = tt.in_dummyData
+		serial.setRespData(tt.in_dummyData)
 		resp, err := im.IssueCommandRespNum(tt.in_cmd, tt.in_param)
 		if (tt.out_errorIsNil && (err != nil)) ||
 			(!tt.out_errorIsNil && (err == nil)) {
@@ -356,7 +395,7 @@ var IssueCommandRespNumsTests = []struct {
 		[]uint16{0x1010}, true,
 	},
 	{
-		"HOGE", "HUGA", []byte("1010\r\n0101\r\n"),
+		"RRID", "", []byte("1010\r\n0101\r\n"),
 		[]uint16{0x1010, 0x0101}, true,
 	},
 	{
@@ -379,10 +418,11 @@ var IssueCommandRespNumsTests = []struct {
 
 func TestIssueCommandRespNums(t *testing.T) {
 	serial := newFakeSerial()
-	im := &IM920{s: serial, m: new(sync.Mutex), readTimeout: 100 * time.Millisecond, rcvedData: list.New()}
+	im := newTestIM920(serial)
+	defer im.Close()
 
 	for i, tt := range IssueCommandRespNumsTests {
-		serial.dummyData = tt.in_dummyData
+		serial.setRespData(tt.in_dummyData)
 		resp, err := im.IssueCommandRespNums(tt.in_cmd, tt.in_param)
 		if (tt.out_errorIsNil && (err != nil)) ||
 			(!tt.out_errorIsNil && (err == nil)) {
@@ -413,10 +453,11 @@ var WriteTests = []struct {
 
 func TestWrite(t *testing.T) {
 	serial := newFakeSerial()
-	im := &IM920{s: serial, m: new(sync.Mutex), readTimeout: 100 * time.Millisecond, rcvedData: list.New()}
+	im := newTestIM920(serial)
+	defer im.Close()
 
 	for i, tt := range WriteTests {
-		serial.dummyData = tt.in_dummyData
+		serial.setRespData(tt.in_dummyData)
 		data := tt.in_writeData
 		_, err := im.Write(data)
 		if (tt.out_errorIsNil && (err != nil)) ||
@@ -442,11 +483,12 @@ var ReadTests = []struct {
 		false,
 	},
 	{
+		// framed lines carry no CRLF
 		nil,
 		[]string{"00,06E5,B5:0A,1F,76"},
-		[]byte{},
-		ReadInfo{},
-		false,
+		[]byte{0x0A, 0x1F, 0x76},
+		ReadInfo{FromNode: 0x00, FromId: 0x06E5, FromRssi: 0xb5},
+		true,
 	},
 	{
 		[]byte("00,06E5,B5:\r\n"),
@@ -478,7 +520,7 @@ var ReadTests = []struct {
 	},
 	{
 		nil,
-		[]string{"00,06E5,B5:0A,1F,76,00,00,00,00,00\r\n"},
+		[]string{"00,06E5,B5:0A,1F,76,00,00,00,00,00"},
 		[]byte{0x0A, 0x1F, 0x76, 0x00, 0x00, 0x00, 0x00, 0x00},
 		ReadInfo{FromNode: 0x00, FromId: 0x06E5, FromRssi: 0xb5},
 		true,
@@ -510,12 +552,12 @@ func TestRead(t *testing.T) {
 	serial := newFakeSerial()
 
 	for i, tt := range ReadTests {
-		im := &IM920{s: serial, m: new(sync.Mutex), readTimeout: 100 * time.Millisecond, rcvedData: list.New()}
+		im := newTestIM920(serial)
 		buf := make([]byte, maxReadSize)
-		serial.dummyData = tt.in
 		for _, v := range tt.in_rcvedData {
-			im.rcvedData.PushBack(v)
+			im.rcvedData <- rcvedLine{line: v}
 		}
+		serial.setDummyData(tt.in)
 		n, err := im.Read(buf)
 		if (tt.out_errorIsNil && (err != nil)) ||
 			(!tt.out_errorIsNil && (err == nil)) {
@@ -527,9 +569,9 @@ func TestRead(t *testing.T) {
 				i, buf, tt.out)
 		}
 		if len(tt.in_rcvedData) != 0 &&
-			im.rcvedData.Len() != len(tt.in_rcvedData)-1 {
+			len(im.rcvedData) != len(tt.in_rcvedData)-1 {
 			t.Errorf("[%d]Read() => %v, want len = %v",
-				i, im.rcvedData.Len(), len(tt.in_rcvedData)-1)
+				i, len(im.rcvedData), len(tt.in_rcvedData)-1)
 		}
 
 		info := im.LastReadInfo()
@@ -537,6 +579,197 @@ func TestRead(t *testing.T) {
 			t.Errorf("[%d]LastReadInfo() => %v, want data = %v",
 				i, info, tt.out_readInfo)
 		}
+		im.Close()
+	}
+}
+
+func TestReaderDemux(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	readErr := make(chan error, 1)
+	go func() {
+		_, err := im.Read(make([]byte, maxReadSize))
+		readErr <- err
+	}()
+
+	serial.setRespData([]byte("OK\r\n"))
+	if err := im.IssueCommandNormal("HOGE", "HUGA"); err != nil {
+		t.Errorf("IssueCommandNormal() during Read => %v, want nil", err)
+	}
+	if err := <-readErr; err != io.EOF {
+		t.Errorf("Read() => %v, want %v", err, io.EOF)
+	}
+
+	serial.setDummyData([]byte("00,06E5,B5:0A\r\n"))
+	time.Sleep(150 * time.Millisecond)
+	serial.setRespData([]byte("OK\r\n"))
+	if err := im.IssueCommandNormal("HOGE", "HUGA"); err != nil {
+		t.Errorf("IssueCommandNormal() => %v, want nil", err)
+	}
+
+	buf := make([]byte, maxReadSize)
+	n, err := im.Read(buf)
+	if err != nil || !bytes.Equal(buf[:n], []byte{0x0A}) {
+		t.Errorf("Read() => %v, %v, want data = %v", buf[:n], err, []byte{0x0A})
+	}
+}
+
+func TestNew(t *testing.T) {
+	conn, module := net.Pipe()
+	im := New(conn, WithReadTimeout(time.Second))
+	defer im.Close()
+
+	go func() {
+		buf := make([]byte, maxReadSize)
+		n, _ := module.Read(buf)
+		if string(buf[:n]) == "RDID \r\n" {
+			module.Write([]byte("06E5\r\n"))
+		}
+	}()
+
+	id, err := im.GetId()
+	if err != nil || id != 0x06E5 {
+		t.Errorf("GetId() over a blocking transport => %v, %v, want %v", id, err, Id(0x06E5))
+	}
+}
+
+// stuckConn is a transport whose Read blocks until the test ends, even
+// after Close.
+type stuckConn struct {
+	unblock chan struct{}
+}
+
+func (c *stuckConn) Read(p []byte) (int, error) {
+	<-c.unblock
+	return 0, io.EOF
+}
+
+func (c *stuckConn) Write(p []byte) (int, error) { return len(p), nil }
+func (c *stuckConn) Close() error                { return nil }
+
+func TestCloseBlockedRead(t *testing.T) {
+	conn := &stuckConn{unblock: make(chan struct{})}
+	defer close(conn.unblock)
+	im := New(conn)
+
+	done := make(chan error, 1)
+	go func() { done <- im.Close() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Close() => %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close() with a Read that never returns => blocked")
+	}
+}
+
+func TestEOF(t *testing.T) {
+	conn, module := net.Pipe()
+	im := New(conn, WithReadTimeout(time.Second))
+	defer im.Close()
+
+	// the peer goes away: io.EOF stops the reader instead of looking
+	// like silence
+	module.Close()
+	if _, err := im.ReadPacket(); err == nil || err == io.EOF {
+		t.Errorf("ReadPacket() after EOF => %v, want reader stopped", err)
+	}
+
+	eof := &stuckConn{unblock: make(chan struct{})}
+	close(eof.unblock)
+	idle := New(eof, WithReadTimeout(50*time.Millisecond), WithIdleEOF(true))
+	defer idle.Close()
+	if _, err := idle.IssueCommand("RDID", ""); !errors.Is(err, ErrNoResponse) {
+		t.Errorf("IssueCommand() with WithIdleEOF => %v, want %v", err, ErrNoResponse)
+	}
+}
+
+var LineTimeoutTests = []struct {
+	in_opts []Option
+	out     bool
+}{
+	// a network transport pausing mid-line keeps the line
+	{nil, true},
+	{[]Option{WithLineTimeout(20 * time.Millisecond)}, false},
+}
+
+func TestLineTimeout(t *testing.T) {
+	for i, tt := range LineTimeoutTests {
+		serial := newFakeSerial()
+		im := New(serial, append(tt.in_opts, WithReadTimeout(300*time.Millisecond))...)
+
+		serial.setDummyData([]byte("00,06E5,B5:"))
+		time.Sleep(100 * time.Millisecond)
+		serial.setDummyData([]byte("0A\r\n"))
+
+		_, err := im.ReadPacket()
+		if (err == nil) != tt.out {
+			t.Errorf("[%d]ReadPacket() after a pause mid-line => %v, want received = %v", i, err, tt.out)
+		}
+
+		im.Close()
+	}
+}
+
+func TestIssueCommandContext(t *testing.T) {
+	serial := newFakeSerial()
+	im := New(serial, WithReadTimeout(time.Second))
+	defer im.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := im.IssueCommandContext(ctx, "HOGE", "HUGA")
+	if err == nil {
+		t.Errorf("IssueCommandContext() => nil, want error")
+	}
+	if d := time.Since(start); d >= im.readTimeout {
+		t.Errorf("IssueCommandContext() took %v, want < %v", d, im.readTimeout)
+	}
+
+	im.IsBusyFunc(func() bool { return true })
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	err = im.IssueCommandNormalContext(ctx, "HOGE", "HUGA")
+	if err == nil {
+		t.Errorf("IssueCommandNormalContext() while busy => nil, want error")
+	}
+	im.IsBusyFunc(nil)
+
+	// a late response to the cancelled command must not leak into the next one
+	serial.setDummyData([]byte("NG\r\n"))
+	time.Sleep(150 * time.Millisecond)
+	serial.setRespData([]byte("OK\r\n"))
+	if err := im.IssueCommandNormal("HOGE", "HUGA"); err != nil {
+		t.Errorf("IssueCommandNormal() after cancel => %v, want nil", err)
+	}
+}
+
+func TestReadContext(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := im.ReadContext(ctx, make([]byte, maxReadSize))
+	if err != context.DeadlineExceeded {
+		t.Errorf("ReadContext() => %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	serial.setDummyData([]byte("00,06E5,B5:0A\r\n"))
+	buf := make([]byte, maxReadSize)
+	n, err := im.ReadContext(ctx, buf)
+	if err != nil || !bytes.Equal(buf[:n], []byte{0x0A}) {
+		t.Errorf("ReadContext() => %v, %v, want data = %v", buf[:n], err, []byte{0x0A})
 	}
 }
 
@@ -555,10 +788,11 @@ var GetIdTests = []struct {
 
 func TestGetId(t *testing.T) {
 	serial := newFakeSerial()
-	im := &IM920{s: serial, m: new(sync.Mutex), readTimeout: 100 * time.Millisecond, rcvedData: list.New()}
+	im := newTestIM920(serial)
+	defer im.Close()
 
 	for i, tt := range GetIdTests {
-		serial.dummyData = tt.in_dummyData
+		serial.setRespData(tt.in_dummyData)
 		id, err := im.GetId()
 		if (tt.out_errorIsNil && (err != nil)) ||
 			(!tt.out_errorIsNil && (err == nil)) {
@@ -603,10 +837,11 @@ var AddRcvIdTests = []struct {
 
 func TestAddRcvId(t *testing.T) {
 	serial := newFakeSerial()
-	im := &IM920{s: serial, m: new(sync.Mutex), readTimeout: 100 * time.Millisecond, rcvedData: list.New()}
+	im := newTestIM920(serial)
+	defer im.Close()
 
 	for i, tt := range AddRcvIdTests {
-		serial.dummyData = tt.in_dummyData
+		serial.setRespData(tt.in_dummyData)
 		err := im.AddRcvId(tt.in)
 		if (tt.out_errorIsNil && (err != nil)) ||
 			(!tt.out_errorIsNil && (err == nil)) {
@@ -654,10 +889,11 @@ var GetAllRcvIdTests = []struct {
 
 func TestGetAllRcvId(t *testing.T) {
 	serial := newFakeSerial()
-	im := &IM920{s: serial, m: new(sync.Mutex), readTimeout: 100 * time.Millisecond, rcvedData: list.New()}
+	im := newTestIM920(serial)
+	defer im.Close()
 
 	for i, tt := range GetAllRcvIdTests {
-		serial.dummyData = tt.in_dummyData
+		serial.setRespData(tt.in_dummyData)
 		ids, err := im.GetAllRcvId()
 		if (tt.out_errorIsNil && (err != nil)) ||
 			(!tt.out_errorIsNil && (err == nil)) {
@@ -670,3 +906,305 @@ func TestGetAllRcvId(t *testing.T) {
 		}
 	}
 }
+
+var ErrorsTests = []struct {
+	in_dummyData []byte
+	in_busy      bool
+	out          error
+}{
+	{[]byte("NG\r\n"), false, ErrNG},
+	{[]byte("HOGE\r\n"), false, ErrUnexpectedResponse},
+	{[]byte("ZZZZ\r\n"), false, ErrUnexpectedResponse},
+	{[]byte("0000"), false, ErrNoResponse},
+	{[]byte(""), false, ErrNoResponse},
+	{[]byte("OK\r\n"), true, ErrBusyTimeout},
+}
+
+func TestErrors(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	for i, tt := range ErrorsTests {
+		im.IsBusyFunc(func() bool { return tt.in_busy })
+		serial.setRespData(tt.in_dummyData)
+		_, err := im.GetId()
+		if !errors.Is(err, tt.out) {
+			t.Errorf("[%d]GetId() => %v, want errors.Is %v", i, err, tt.out)
+		}
+
+		var cerr *CommandError
+		if !errors.As(err, &cerr) {
+			t.Errorf("[%d]GetId() => %v, want *CommandError", i, err)
+		} else if cerr.Cmd != "RDID" {
+			t.Errorf("[%d]GetId() => %v, want Cmd = RDID", i, cerr.Cmd)
+		}
+	}
+	im.IsBusyFunc(nil)
+
+	serial.setDummyData([]byte("00,06E5,B5:ZZ\r\n"))
+	_, err := im.Read(make([]byte, maxReadSize))
+	var ferr *FrameError
+	if !errors.As(err, &ferr) {
+		t.Errorf("Read() => %v, want *FrameError", err)
+	} else if ferr.Line != "00,06E5,B5:ZZ" {
+		t.Errorf("Read() => %q, want Line = %q", ferr.Line, "00,06E5,B5:ZZ")
+	}
+}
+
+func TestSetBaudRate(t *testing.T) {
+	serial := newFakeSerial()
+	serial.setRespData([]byte("OK\r\n"), []byte("OK\r\n"))
+
+	var reopened []int
+	ports := []*fakeSerial{newFakeSerial()}
+	ports[0].setRespData([]byte("06E5\r\n"), []byte("OK\r\n"))
+	reopen := func(baud int) (io.ReadWriteCloser, error) {
+		reopened = append(reopened, baud)
+		p := ports[0]
+		ports = ports[1:]
+		return p, nil
+	}
+
+	im := New(serial, WithReadTimeout(100*time.Millisecond), WithBaudRate(19200), WithReopen(reopen))
+	defer im.Close()
+
+	if err := im.SetBaudRate(9999, false); err == nil {
+		t.Errorf("SetBaudRate(9999) => nil, want error")
+	}
+
+	port := ports[0]
+	if err := im.SetBaudRate(115200, true); err != nil {
+		t.Errorf("SetBaudRate(115200) => %v, want nil", err)
+	}
+	if !bytes.Equal(serial.getWritedData(), []byte("SBRT 7\r\n")) {
+		t.Errorf("SetBaudRate() wrote %q, want %q", serial.getWritedData(), "SBRT 7\r\n")
+	}
+	if !bytes.Equal(port.getWritedData(), []byte("DSWR \r\n")) {
+		t.Errorf("SetBaudRate() wrote %q at new rate, want %q", port.getWritedData(), "DSWR \r\n")
+	}
+	if !reflect.DeepEqual(reopened, []int{115200}) || im.BaudRate() != 115200 {
+		t.Errorf("SetBaudRate() reopened at %v, BaudRate() = %v, want 115200", reopened, im.BaudRate())
+	}
+
+	// no answer at the new rate: fall back to the old one
+	reopened = nil
+	port.setRespData([]byte("OK\r\n"))
+	ports = []*fakeSerial{newFakeSerial(), newFakeSerial()}
+	ports[1].setRespData([]byte("06E5\r\n"))
+	if err := im.SetBaudRate(9600, false); err == nil {
+		t.Errorf("SetBaudRate(9600) without answer => nil, want error")
+	}
+	if !reflect.DeepEqual(reopened, []int{9600, 115200}) || im.BaudRate() != 115200 {
+		t.Errorf("SetBaudRate() reopened at %v, BaudRate() = %v, want back at 115200", reopened, im.BaudRate())
+	}
+}
+
+var WireTimeTests = []struct {
+	in_baud int
+	in_n    int
+	out     time.Duration
+}{
+	{1200, 6, 50 * time.Millisecond},
+	{4800, 24, 50 * time.Millisecond},
+	{115200, 32, 2777777 * time.Nanosecond},
+}
+
+func TestWireTime(t *testing.T) {
+	for i, tt := range WireTimeTests {
+		if got := wireTime(tt.in_baud, tt.in_n); got != tt.out {
+			t.Errorf("[%d]wireTime(%d, %d) => %v, want %v", i, tt.in_baud, tt.in_n, got, tt.out)
+		}
+	}
+}
+
+func TestRespGapByBaud(t *testing.T) {
+	serial := newFakeSerial()
+	im := New(serial, WithReadTimeout(time.Second), WithBaudRate(1200))
+	defer im.Close()
+
+	// at 1200 bps the second line of RRID takes longer than respGap
+	serial.setRespData([]byte("0001\r\n"))
+	go func() {
+		time.Sleep(respGap + wireTime(1200, len("0002\r\n")))
+		serial.setDummyData([]byte("0002\r\n"))
+	}()
+
+	ids, err := im.GetAllRcvId()
+	if err != nil || !reflect.DeepEqual(ids, []Id{0x0001, 0x0002}) {
+		t.Errorf("GetAllRcvId() at 1200 bps => %v, %v, want %v", ids, err, []Id{0x0001, 0x0002})
+	}
+}
+
+func TestOpenAuto(t *testing.T) {
+	var tried []int
+	open := func(baud int) (io.ReadWriteCloser, error) {
+		tried = append(tried, baud)
+		serial := newFakeSerial()
+		if baud == 57600 {
+			serial.setRespData([]byte("NG\r\n"), []byte("06E5\r\n"))
+		} else {
+			serial.setRespData([]byte("\x8f\xf0"), []byte("\x00\r\n"))
+		}
+		return serial, nil
+	}
+
+	im, err := openAuto(open, 19200, WithReadTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatalf("openAuto() => %v, want nil", err)
+	}
+	defer im.Close()
+
+	if im.BaudRate() != 57600 {
+		t.Errorf("BaudRate() => %v, want 57600", im.BaudRate())
+	}
+	if !reflect.DeepEqual(tried, []int{19200, 115200, 57600}) {
+		t.Errorf("openAuto() tried %v, want %v", tried, []int{19200, 115200, 57600})
+	}
+
+	open = func(baud int) (io.ReadWriteCloser, error) {
+		return newFakeSerial(), nil
+	}
+	if _, err := openAuto(open, 19200, WithReadTimeout(10*time.Millisecond)); !errors.Is(err, ErrBaudNotDetected) {
+		t.Errorf("openAuto() without answer => %v, want %v", err, ErrBaudNotDetected)
+	}
+}
+
+func TestReadPacket(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	before := time.Now()
+	serial.setDummyData([]byte("01,06E6,B6:0A,1F\r\n00,06E5,B5:ZZ\r\n"))
+
+	pkt, err := im.ReadPacket()
+	if err != nil {
+		t.Fatalf("ReadPacket() => %v, want nil", err)
+	}
+	want := Packet{
+		ReadInfo: ReadInfo{FromNode: 0x01, FromId: 0x06E6, FromRssi: 0xb6},
+		Data:     []byte{0x0A, 0x1F},
+		Raw:      "01,06E6,B6:0A,1F",
+	}
+	if pkt.ReadInfo != want.ReadInfo || !bytes.Equal(pkt.Data, want.Data) || pkt.Raw != want.Raw {
+		t.Errorf("ReadPacket() => %+v, want %+v", pkt, want)
+	}
+	if pkt.Time.Before(before) || pkt.Time.After(time.Now()) {
+		t.Errorf("ReadPacket() => Time %v, want between %v and now", pkt.Time, before)
+	}
+
+	pkt, err = im.ReadPacket()
+	var ferr *FrameError
+	if !errors.As(err, &ferr) || pkt.Raw != "00,06E5,B5:ZZ" {
+		t.Errorf("ReadPacket() => %+v, %v, want *FrameError with Raw", pkt, err)
+	}
+
+	if _, err := im.ReadPacket(); err != io.EOF {
+		t.Errorf("ReadPacket() => %v, want %v", err, io.EOF)
+	}
+}
+
+func TestCharIOMode(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	serial.setRespData([]byte("OK\r\n"))
+	if err := im.SetCharIOMode(true, false); err != nil {
+		t.Fatalf("SetCharIOMode(true) => %v, want nil", err)
+	}
+	if !im.GetCharIOMode() {
+		t.Errorf("GetCharIOMode() => false, want true")
+	}
+	if !bytes.HasPrefix(serial.getWritedData(), []byte("ECIO")) {
+		t.Errorf("SetCharIOMode(true) => wrote %q, want ECIO", serial.getWritedData())
+	}
+
+	serial.setRespData([]byte("OK\r\n"))
+	if n, err := im.Write([]byte("Hello,IM920")); err != nil || n != 11 {
+		t.Errorf("Write() => %v, %v, want 11, nil", n, err)
+	}
+	if !bytes.Equal(serial.getWritedData(), []byte("TXDA Hello,IM920\r\n")) {
+		t.Errorf("Write() => wrote %q, want %q", serial.getWritedData(), "TXDA Hello,IM920\r\n")
+	}
+	if _, err := im.Write([]byte{0x0A}); err == nil {
+		t.Errorf("Write(0x0A) in character I/O mode => nil, want error")
+	}
+
+	serial.setDummyData([]byte("00,06E5,B5:Hello,IM920\r\n"))
+	pkt, err := im.ReadPacket()
+	if err != nil || string(pkt.Data) != "Hello,IM920" {
+		t.Errorf("ReadPacket() => %q, %v, want %q, nil", pkt.Data, err, "Hello,IM920")
+	}
+
+	serial.setRespData([]byte("OK\r\n"))
+	if err := im.SetCharIOMode(false, false); err != nil {
+		t.Fatalf("SetCharIOMode(false) => %v, want nil", err)
+	}
+	serial.setDummyData([]byte("00,06E5,B5:0A,1F\r\n"))
+	buf := make([]byte, 8)
+	n, err := im.Read(buf)
+	if err != nil || !bytes.Equal(buf[:n], []byte{0x0A, 0x1F}) {
+		t.Errorf("Read() => %v, %v, want [10 31], nil", buf[:n], err)
+	}
+}
+
+func TestNode(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	serial.setRespData([]byte("01\r\n"))
+	node, err := im.GetNode()
+	if err != nil || node != 0x01 {
+		t.Errorf("GetNode() => %v, %v, want 1, nil", node, err)
+	}
+
+	serial.setRespData([]byte("OK\r\n"))
+	if err := im.SetNode(0x0A, false); err != nil {
+		t.Errorf("SetNode() => %v, want nil", err)
+	}
+	if !bytes.HasPrefix(serial.getWritedData(), []byte("STNN 0a")) {
+		t.Errorf("SetNode() => wrote %q, want STNN 0a", serial.getWritedData())
+	}
+}
+
+var SetTxPowerTests = []struct {
+	in             Power
+	out_writedData []byte
+	out_errorIsNil bool
+}{
+	{POWER_MINUS_10DBM, []byte("STPO 1\r\n"), true},
+	{POWER_10DBM, []byte("STPO 3\r\n"), true},
+	{0, nil, false},
+	{4, nil, false},
+}
+
+func TestSetTxPower(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	for i, tt := range SetTxPowerTests {
+		serial.takeWritedAll()
+		serial.setRespData([]byte("OK\r\n"))
+		err := im.SetTxPower(tt.in, false)
+		if (err == nil) != tt.out_errorIsNil {
+			t.Errorf("[%d]SetTxPower(%v) => %v, want errorIsNil = %v", i, tt.in, err, tt.out_errorIsNil)
+		}
+		writes := serial.takeWritedAll()
+		if tt.out_writedData == nil && len(writes) != 0 {
+			t.Errorf("[%d]SetTxPower(%v) => wrote %q, want nothing", i, tt.in, writes)
+		}
+		if tt.out_writedData != nil && (len(writes) != 1 || !bytes.Equal(writes[0], tt.out_writedData)) {
+			t.Errorf("[%d]SetTxPower(%v) => wrote %q, want %q", i, tt.in, writes, tt.out_writedData)
+		}
+	}
+
+	serial.setRespData([]byte("2\r\n"))
+	power, err := im.GetTxPower()
+	if err != nil || power != POWER_0DBM {
+		t.Errorf("GetTxPower() => %v, %v, want %v, nil", power, err, POWER_0DBM)
+	}
+}
@@ -0,0 +1,174 @@
+package im920
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SLNode is the node number an IM920sL is addressed by.
+type SLNode uint16
+
+// IM920sL drives an IM920sL. It shares the transport, framing, command
+// handling and subscriptions of IM920, but addresses modules by a node
+// number set with SetNode instead of registering sender IDs, and can
+// send to a single node or a group as well as to everyone.
+//
+// Received packets carry the sender's node number in FromSLNode.
+type IM920sL struct {
+	im *IM920
+}
+
+var _ Sender = (*IM920sL)(nil)
+
+// NewIM920sL is New for an IM920sL.
+func NewIM920sL(rw io.ReadWriteCloser, opts ...Option) *IM920sL {
+	return &IM920sL{im: New(rw, append(opts, withHeaders(parseSLHeaders))...)}
+}
+
+// OpenIM920sL is Open for an IM920sL.
+func OpenIM920sL(c *Config) (*IM920sL, error) {
+	im, err := openConfig(c, withHeaders(parseSLHeaders))
+
+	return &IM920sL{im: im}, err
+}
+
+func withHeaders(f func(string) (ReadInfo, error)) Option {
+	return func(im *IM920) {
+		im.headers = f
+	}
+}
+
+// parseSLHeaders parses the header of an IM920sL received line,
+// "DD,NNNN,RR": a dummy field, the sender's node number and the RSSI.
+func parseSLHeaders(s string) (info ReadInfo, err error) {
+	headers := strings.Split(s, ",")
+	if len(headers) != 3 || len(headers[0]) != 2 || len(headers[1]) != 4 {
+		err = fmt.Errorf("error: Split headers failed: %s", s)
+		return
+	}
+
+	node, derr := strToUint16(headers[1])
+	if derr != nil {
+		err = fmt.Errorf("error: Decode node failed (%s): %w", headers[1], derr)
+		return
+	}
+	info.FromSLNode = SLNode(node)
+
+	rssi, derr := strToUint16(headers[2])
+	if derr != nil || len(headers[2]) != 2 {
+		err = fmt.Errorf("error: Decode Rssi failed (%s): %v", headers[2], derr)
+		return
+	}
+	info.FromRssi = Rssi(rssi)
+
+	return
+}
+
+func (sl *IM920sL) Close() error {
+	return sl.im.Close()
+}
+
+func (sl *IM920sL) Model() Model {
+	return sl.im.Model()
+}
+
+func (sl *IM920sL) IssueCommandContext(ctx context.Context, cmd, param string) ([]byte, error) {
+	return sl.im.IssueCommandContext(ctx, cmd, param)
+}
+
+func (sl *IM920sL) ReadPacket() (Packet, error) {
+	return sl.im.ReadPacket()
+}
+
+func (sl *IM920sL) ReadPacketContext(ctx context.Context) (Packet, error) {
+	return sl.im.ReadPacketContext(ctx)
+}
+
+func (sl *IM920sL) Subscribe(buffer int) (<-chan Packet, func()) {
+	return sl.im.Subscribe(buffer)
+}
+
+func (sl *IM920sL) OnReceive(f func(Packet)) (cancel func()) {
+	return sl.im.OnReceive(f)
+}
+
+func (sl *IM920sL) GetVersionContext(ctx context.Context) (Version, error) {
+	return sl.im.GetVersionContext(ctx)
+}
+
+func (sl *IM920sL) SetChContext(ctx context.Context, ch Ch, persist bool) error {
+	return sl.im.SetChContext(ctx, ch, persist)
+}
+
+func (sl *IM920sL) GetChContext(ctx context.Context) (Ch, error) {
+	return sl.im.GetChContext(ctx)
+}
+
+func (sl *IM920sL) SetGroupContext(ctx context.Context, group Group, persist bool) error {
+	return sl.im.SetGroupContext(ctx, group, persist)
+}
+
+func (sl *IM920sL) GetGroupContext(ctx context.Context) (Group, error) {
+	return sl.im.GetGroupContext(ctx)
+}
+
+func (sl *IM920sL) SetCharIOModeContext(ctx context.Context, enabled, persist bool) error {
+	return sl.im.SetCharIOModeContext(ctx, enabled, persist)
+}
+
+// SetNodeContext sets the node number other modules send to.
+func (sl *IM920sL) SetNodeContext(ctx context.Context, node SLNode, persist bool) error {
+	return sl.im.writeEnable(ctx, persist, func() error {
+		ierr := sl.im.IssueCommandNormalContext(ctx, "STNN", fmt.Sprintf("%04X", node))
+		if ierr != nil {
+			return fmt.Errorf("error: STNN failed: %w", ierr)
+		}
+
+		return nil
+	})
+}
+
+func (sl *IM920sL) GetNodeContext(ctx context.Context) (node SLNode, err error) {
+	rcv, ierr := sl.im.IssueCommandRespNumContext(ctx, "RDNN", "")
+	if ierr != nil {
+		err = fmt.Errorf("error: RDNN failed: %w", ierr)
+		return
+	}
+
+	node = SLNode(rcv)
+
+	return
+}
+
+// Send transmits p to every module in range (TXDA), to node to.Node
+// (TXDU) or to the modules in group to.Group (TXDG).
+func (sl *IM920sL) Send(ctx context.Context, to Dest, p []byte) error {
+	if len(p) > maxTXDA {
+		return fmt.Errorf("error: too much data (%d > %d bytes)", len(p), maxTXDA)
+	}
+
+	data, err := sl.im.encodePayload(p)
+	if err != nil {
+		return err
+	}
+
+	var cmd, param string
+	switch to.Kind {
+	case DEST_BROADCAST:
+		cmd, param = "TXDA", data
+	case DEST_UNICAST:
+		cmd, param = "TXDU", fmt.Sprintf("%04X,%s", to.To, data)
+	case DEST_GROUP:
+		cmd, param = "TXDG", fmt.Sprintf("%02X,%s", to.Group, data)
+	default:
+		return fmt.Errorf("error: invalid destination (%s)", to.Kind)
+	}
+
+	if err = sl.im.IssueCommandNormalContext(ctx, cmd, param); err != nil {
+		return fmt.Errorf("error: %s failed: %w", cmd, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package im920
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var ParseSLHeadersTests = []struct {
+	in             string
+	out            ReadInfo
+	out_errorIsNil bool
+}{
+	{"00,0001,D0", ReadInfo{FromSLNode: 0x0001, FromRssi: 0xD0}, true},
+	{"00,FFEF,9A", ReadInfo{FromSLNode: 0xFFEF, FromRssi: 0x9A}, true},
+	// an IM920 line with a 1 digit node field
+	{"0,06E5,D0", ReadInfo{}, false},
+	{"00,01,D0", ReadInfo{}, false},
+	{"00,0001", ReadInfo{}, false},
+	{"00,0001,D0,00", ReadInfo{}, false},
+}
+
+func TestParseSLHeaders(t *testing.T) {
+	for i, tt := range ParseSLHeadersTests {
+		info, err := parseSLHeaders(tt.in)
+		if (err == nil) != tt.out_errorIsNil {
+			t.Errorf("[%d]parseSLHeaders(%q) => %v, want errorIsNil = %v", i, tt.in, err, tt.out_errorIsNil)
+		}
+		if err == nil && info != tt.out {
+			t.Errorf("[%d]parseSLHeaders(%q) => %+v, want %+v", i, tt.in, info, tt.out)
+		}
+	}
+}
+
+var SLSendTests = []struct {
+	in_to          Dest
+	in_data        []byte
+	in_respData    []byte
+	out_cmd        string
+	out_errorIsNil bool
+}{
+	{Broadcast(), []byte{0x01, 0xAB}, []byte("OK\r\n"), "TXDA 01AB\r\n", true},
+	{Unicast(0x0002), []byte{0x01, 0xAB}, []byte("OK\r\n"), "TXDU 0002,01AB\r\n", true},
+	{ToGroup(0x1F), []byte{0x01}, []byte("OK\r\n"), "TXDG 1F,01\r\n", true},
+	{Unicast(0x0002), []byte{0x01}, []byte("NG\r\n"), "TXDU 0002,01\r\n", false},
+	{Broadcast(), make([]byte, maxTXDA+1), nil, "", false},
+	{Dest{Kind: 9}, []byte{0x01}, nil, "", false},
+}
+
+func TestIM920sLSend(t *testing.T) {
+	serial := newFakeSerial()
+	sl := NewIM920sL(serial, WithReadTimeout(100*time.Millisecond))
+	defer sl.Close()
+
+	for i, tt := range SLSendTests {
+		serial.takeWritedAll()
+		serial.setRespData(tt.in_respData)
+		err := sl.Send(context.Background(), tt.in_to, tt.in_data)
+		if (err == nil) != tt.out_errorIsNil {
+			t.Errorf("[%d]Send(%s) => %v, want errorIsNil = %v", i, tt.in_to.Kind, err, tt.out_errorIsNil)
+		}
+
+		var want []string
+		if tt.out_cmd != "" {
+			want = []string{tt.out_cmd}
+		}
+		got := writesOf(serial.takeWritedAll())
+		if len(got) == 0 {
+			got = nil
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("[%d]Send(%s) => wrote %q, want %q", i, tt.in_to.Kind, got, want)
+		}
+	}
+}
+
+func TestIM920sLReceive(t *testing.T) {
+	serial := newFakeSerial()
+	sl := NewIM920sL(serial, WithReadTimeout(100*time.Millisecond))
+	defer sl.Close()
+
+	ch, cancel := sl.Subscribe(4)
+	defer cancel()
+
+	serial.setRespData([]byte("0002\r\n"))
+	if node, err := sl.GetNodeContext(context.Background()); err != nil || node != 0x0002 {
+		t.Errorf("GetNode() => %04X, %v, want 0002", node, err)
+	}
+
+	serial.setDummyData([]byte("00,0003,C8:0A,0B\r\n"))
+	select {
+	case pkt := <-ch:
+		if !bytes.Equal(pkt.Data, []byte{0x0A, 0x0B}) || pkt.FromSLNode != 0x0003 || pkt.FromId != 0 || pkt.FromRssi != 0xC8 {
+			t.Errorf("Subscribe() => %+v, want data 0A0B from node 0003", pkt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Subscribe() => no packet")
+	}
+}
+
+func TestIM920Send(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	serial.setRespData([]byte("OK\r\n"))
+	if err := im.Send(context.Background(), Broadcast(), []byte{0x01}); err != nil {
+		t.Errorf("Send(broadcast) => %v, want nil", err)
+	}
+	if got := string(serial.getWritedData()); got != "TXDA 01\r\n" {
+		t.Errorf("Send(broadcast) => wrote %q, want %q", got, "TXDA 01\r\n")
+	}
+
+	for _, to := range []Dest{Unicast(0x0002), ToGroup(0x01)} {
+		if err := im.Send(context.Background(), to, []byte{0x01}); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Send(%s) => %v, want %v", to.Kind, err, ErrUnsupported)
+		}
+	}
+}
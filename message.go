@@ -0,0 +1,516 @@
+package im920
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Every TXDA sent by a Messenger starts with a header of
+//
+//	type(1) message ID(2, big endian) index(1) count(1)
+//
+// so a message of up to maxFragments*maxFragmentData bytes is split
+// into count fragments that the receiving Messenger puts back together.
+// Fragments of type frameReliable ask the receiver to confirm the
+// complete message with an ACK frame of
+//
+//	type(1) message ID(2) ID of the sender being acknowledged(2)
+//
+// The message ID doubles as the sequence number for duplicate
+// suppression.
+const (
+	frameFragment byte = 0xF1
+	frameAck      byte = 0xF2
+	frameReliable byte = 0xF3
+
+	ackLen = 5
+
+	fragmentHeaderLen = 5
+	maxFragmentData   = maxTXDA - fragmentHeaderLen
+	maxFragments      = 255
+	MaxMessageSize    = maxFragments * maxFragmentData
+
+	defaultReassemblyTimeout = 5 * time.Second
+	dedupWindow              = 1 * time.Minute
+	defaultRetries           = 3
+	defaultAckTimeout        = 500 * time.Millisecond
+	minSweepInterval         = time.Millisecond
+	ackQueueLen              = 16
+)
+
+// Message is a payload reassembled from one or more fragments. ReadInfo
+// and Time are those of the fragment that completed it.
+type Message struct {
+	ReadInfo
+	Data []byte
+	Time time.Time
+}
+
+type MessengerOption func(*Messenger)
+
+// WithRetries sets how many times SendReliable retransmits a message
+// that has not been acknowledged. A negative n counts as 0.
+func WithRetries(n int) MessengerOption {
+	return func(m *Messenger) {
+		m.retries = n
+	}
+}
+
+// WithAckTimeout sets how long SendReliable waits for an ACK after the
+// first transmission. The wait doubles with every retransmission. A
+// non-positive d leaves the default.
+func WithAckTimeout(d time.Duration) MessengerOption {
+	return func(m *Messenger) {
+		m.ackTimeout = d
+	}
+}
+
+// WithReassemblyTimeout sets how long a partly received message is kept
+// waiting for its missing fragments. A non-positive d leaves the
+// default.
+func WithReassemblyTimeout(d time.Duration) MessengerOption {
+	return func(m *Messenger) {
+		m.timeout = d
+	}
+}
+
+// Messenger sends and receives messages larger than a single TXDA on
+// top of an IM920. Both ends must use a Messenger; plain packets that
+// do not carry its header are ignored.
+type Messenger struct {
+	im         *IM920
+	timeout    time.Duration
+	retries    int
+	ackTimeout time.Duration
+	idM        sync.Mutex
+	nextID     uint16
+	myId       Id
+	hasMyId    bool
+	acks       map[ackKey]chan struct{}
+	msgs       chan Message
+	cancel     func()
+	done       chan struct{}
+	ackQueue   chan ackOut
+	ackFailed  int
+	ackCancel  func()
+	ackerDone  chan struct{}
+}
+
+type ackOut struct {
+	msgID uint16
+	to    Id
+}
+
+// DeliveryReport describes an acknowledged message.
+type DeliveryReport struct {
+	Attempts int
+	Elapsed  time.Duration
+}
+
+type ackKey struct {
+	peer  Id
+	msgID uint16
+}
+
+type msgKey struct {
+	id    Id
+	node  Node
+	msgID uint16
+}
+
+type partialMsg struct {
+	frags   [][]byte
+	have    int
+	started time.Time
+}
+
+type fragment struct {
+	reliable bool
+	msgID    uint16
+	index    int
+	count    int
+	data     []byte
+}
+
+func NewMessenger(im *IM920, opts ...MessengerOption) *Messenger {
+	m := &Messenger{
+		im:         im,
+		timeout:    defaultReassemblyTimeout,
+		retries:    defaultRetries,
+		ackTimeout: defaultAckTimeout,
+		nextID:     uint16(time.Now().UnixNano()),
+		acks:       make(map[ackKey]chan struct{}),
+		msgs:       make(chan Message, maxRcvedData),
+		done:       make(chan struct{}),
+		ackQueue:   make(chan ackOut, ackQueueLen),
+		ackerDone:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.timeout <= 0 {
+		m.timeout = defaultReassemblyTimeout
+	}
+	if m.retries < 0 {
+		m.retries = 0
+	}
+	if m.ackTimeout <= 0 {
+		m.ackTimeout = defaultAckTimeout
+	}
+
+	ctx, ackCancel := context.WithCancel(context.Background())
+	m.ackCancel = ackCancel
+	go m.sendAcks(ctx)
+
+	ch, cancel := im.Subscribe(maxRcvedData)
+	m.cancel = cancel
+	go m.run(ch)
+
+	return m
+}
+
+func encodeFragment(f fragment) []byte {
+	b := make([]byte, fragmentHeaderLen, fragmentHeaderLen+len(f.data))
+	b[0] = frameFragment
+	if f.reliable {
+		b[0] = frameReliable
+	}
+	binary.BigEndian.PutUint16(b[1:3], f.msgID)
+	b[3] = byte(f.index)
+	b[4] = byte(f.count)
+
+	return append(b, f.data...)
+}
+
+func decodeFragment(b []byte) (f fragment, ok bool) {
+	if len(b) < fragmentHeaderLen || (b[0] != frameFragment && b[0] != frameReliable) {
+		return
+	}
+
+	f.reliable = b[0] == frameReliable
+	f.msgID = binary.BigEndian.Uint16(b[1:3])
+	f.index = int(b[3])
+	f.count = int(b[4])
+	f.data = b[fragmentHeaderLen:]
+	if f.count == 0 || f.index >= f.count {
+		return
+	}
+
+	return f, true
+}
+
+func encodeAck(msgID uint16, to Id) []byte {
+	b := make([]byte, ackLen)
+	b[0] = frameAck
+	binary.BigEndian.PutUint16(b[1:3], msgID)
+	binary.BigEndian.PutUint16(b[3:5], uint16(to))
+
+	return b
+}
+
+func decodeAck(b []byte) (msgID uint16, to Id, ok bool) {
+	if len(b) != ackLen || b[0] != frameAck {
+		return
+	}
+
+	return binary.BigEndian.Uint16(b[1:3]), Id(binary.BigEndian.Uint16(b[3:5])), true
+}
+
+func (m *Messenger) newMsgID() uint16 {
+	m.idM.Lock()
+	defer m.idM.Unlock()
+
+	m.nextID++
+
+	return m.nextID
+}
+
+// SendMessage transmits p, split into as many TXDA frames as needed.
+// Like Write, it only reports whether the frames left the module.
+func (m *Messenger) SendMessage(ctx context.Context, p []byte) error {
+	if len(p) > MaxMessageSize {
+		return fmt.Errorf("error: message too large (%d > %d)", len(p), MaxMessageSize)
+	}
+
+	return m.sendFragments(ctx, false, m.newMsgID(), p)
+}
+
+// SendReliable transmits p like SendMessage and waits until peer
+// acknowledges it, retransmitting with exponential backoff. It returns
+// ErrNotAcknowledged if no ACK arrives after the configured retries.
+func (m *Messenger) SendReliable(ctx context.Context, peer Id, p []byte) (report DeliveryReport, err error) {
+	if len(p) > MaxMessageSize {
+		err = fmt.Errorf("error: message too large (%d > %d)", len(p), MaxMessageSize)
+		return
+	}
+
+	if _, ierr := m.ownId(ctx); ierr != nil {
+		err = fmt.Errorf("error: GetId failed: %w", ierr)
+		return
+	}
+
+	msgID := m.newMsgID()
+	k := ackKey{peer: peer, msgID: msgID}
+	acked := make(chan struct{})
+
+	m.idM.Lock()
+	m.acks[k] = acked
+	m.idM.Unlock()
+	defer func() {
+		m.idM.Lock()
+		delete(m.acks, k)
+		m.idM.Unlock()
+	}()
+
+	start := time.Now()
+	wait := m.ackTimeout
+
+	for report.Attempts <= m.retries {
+		report.Attempts++
+		err = m.sendFragments(ctx, true, msgID, p)
+		if err != nil {
+			return
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-acked:
+			timer.Stop()
+			report.Elapsed = time.Since(start)
+			return
+		case <-ctx.Done():
+			timer.Stop()
+			err = ctx.Err()
+			return
+		case <-m.done:
+			timer.Stop()
+			err = ErrClosed
+			return
+		case <-timer.C:
+		}
+		wait *= 2
+	}
+
+	report.Elapsed = time.Since(start)
+	err = ErrNotAcknowledged
+
+	return
+}
+
+func (m *Messenger) ownId(ctx context.Context) (Id, error) {
+	m.idM.Lock()
+	id, ok := m.myId, m.hasMyId
+	m.idM.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	id, err := m.im.GetIdContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	m.idM.Lock()
+	m.myId, m.hasMyId = id, true
+	m.idM.Unlock()
+
+	return id, nil
+}
+
+func (m *Messenger) sendFragments(ctx context.Context, reliable bool, msgID uint16, p []byte) error {
+	count := (len(p) + maxFragmentData - 1) / maxFragmentData
+	if count == 0 {
+		count = 1
+	}
+
+	for i := 0; i < count; i++ {
+		end := (i + 1) * maxFragmentData
+		if end > len(p) {
+			end = len(p)
+		}
+
+		frame := encodeFragment(fragment{reliable: reliable, msgID: msgID, index: i, count: count, data: p[i*maxFragmentData : end]})
+		if _, err := m.im.WriteContext(ctx, frame); err != nil {
+			return fmt.Errorf("error: fragment %d/%d failed: %w", i+1, count, err)
+		}
+	}
+
+	return nil
+}
+
+// ReceiveMessage waits for the next complete message until ctx is done.
+func (m *Messenger) ReceiveMessage(ctx context.Context) (Message, error) {
+	select {
+	case msg := <-m.msgs:
+		return msg, nil
+	default:
+	}
+
+	select {
+	case msg := <-m.msgs:
+		return msg, nil
+	case <-m.done:
+		return Message{}, ErrClosed
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	}
+}
+
+// Close stops receiving and sending ACKs. It does not close the
+// underlying IM920.
+func (m *Messenger) Close() error {
+	m.cancel()
+	<-m.done
+	m.ackCancel()
+	<-m.ackerDone
+
+	return nil
+}
+
+func (m *Messenger) run(ch <-chan Packet) {
+	defer close(m.done)
+
+	partials := make(map[msgKey]*partialMsg)
+	completed := make(map[msgKey]time.Time)
+
+	sweep := m.timeout / 2
+	if sweep < minSweepInterval {
+		sweep = minSweepInterval
+	}
+	ticker := time.NewTicker(sweep)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case pkt, ok := <-ch:
+			if !ok {
+				return
+			}
+			if !m.handleAck(pkt) {
+				m.handleFragment(pkt, partials, completed)
+			}
+		case now := <-ticker.C:
+			for k, p := range partials {
+				if now.Sub(p.started) > m.timeout {
+					delete(partials, k)
+				}
+			}
+			for k, t := range completed {
+				if now.Sub(t) > dedupWindow {
+					delete(completed, k)
+				}
+			}
+		}
+	}
+}
+
+func (m *Messenger) handleFragment(pkt Packet, partials map[msgKey]*partialMsg, completed map[msgKey]time.Time) {
+	f, ok := decodeFragment(pkt.Data)
+	if !ok {
+		return
+	}
+
+	k := msgKey{id: pkt.FromId, node: pkt.FromNode, msgID: f.msgID}
+	if _, dup := completed[k]; dup {
+		// the sender missed our ACK and retransmitted
+		if f.reliable {
+			m.ack(f.msgID, pkt.FromId)
+		}
+		return
+	}
+
+	p := partials[k]
+	if p == nil || len(p.frags) != f.count {
+		p = &partialMsg{frags: make([][]byte, f.count), started: pkt.Time}
+		partials[k] = p
+	}
+	if p.frags[f.index] != nil {
+		return
+	}
+	p.frags[f.index] = f.data
+	p.have++
+	if p.have < f.count {
+		return
+	}
+
+	delete(partials, k)
+	completed[k] = pkt.Time
+	if f.reliable {
+		m.ack(f.msgID, pkt.FromId)
+	}
+
+	var data []byte
+	for _, v := range p.frags {
+		data = append(data, v...)
+	}
+	pushLatest(m.msgs, Message{ReadInfo: pkt.ReadInfo, Data: data, Time: pkt.Time})
+}
+
+// handleAck wakes up SendReliable if pkt acknowledges one of our
+// messages. It reports whether pkt was an ACK frame.
+func (m *Messenger) handleAck(pkt Packet) bool {
+	msgID, to, ok := decodeAck(pkt.Data)
+	if !ok {
+		return false
+	}
+
+	m.idM.Lock()
+	defer m.idM.Unlock()
+
+	if !m.hasMyId || to != m.myId {
+		return true
+	}
+
+	k := ackKey{peer: pkt.FromId, msgID: msgID}
+	if acked, ok := m.acks[k]; ok {
+		close(acked)
+		delete(m.acks, k)
+	}
+
+	return true
+}
+
+// ack queues an ACK for sendAcks, so that a slow TXDA does not hold up
+// reassembly. If the queue is full the ACK is dropped; the sender will
+// retransmit and be acknowledged then.
+func (m *Messenger) ack(msgID uint16, to Id) {
+	select {
+	case m.ackQueue <- ackOut{msgID: msgID, to: to}:
+	default:
+		m.ackFailure()
+	}
+}
+
+func (m *Messenger) sendAcks(ctx context.Context) {
+	defer close(m.ackerDone)
+
+	for {
+		select {
+		case a := <-m.ackQueue:
+			if _, err := m.im.WriteContext(ctx, encodeAck(a.msgID, a.to)); err != nil {
+				m.ackFailure()
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (m *Messenger) ackFailure() {
+	m.idM.Lock()
+	defer m.idM.Unlock()
+
+	m.ackFailed++
+}
+
+// AckFailures reports how many ACKs could not be sent, either because
+// TXDA failed or because too many were waiting.
+func (m *Messenger) AckFailures() int {
+	m.idM.Lock()
+	defer m.idM.Unlock()
+
+	return m.ackFailed
+}
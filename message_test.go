@@ -0,0 +1,248 @@
+package im920
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rcvedLineOf formats data as a line received from id.
+func rcvedLineOf(id Id, data []byte) string {
+	strs := make([]string, len(data))
+	for i, v := range data {
+		strs[i] = strings.ToUpper(hex.EncodeToString([]byte{v}))
+	}
+
+	return "00," + strings.ToUpper(hex.EncodeToString([]byte{byte(id >> 8), byte(id)})) + ",B5:" + strings.Join(strs, ",") + "\r\n"
+}
+
+// txdaData decodes the payload of a TXDA command line.
+func txdaData(t *testing.T, cmd []byte) []byte {
+	s := strings.TrimSuffix(strings.TrimPrefix(string(cmd), "TXDA "), "\r\n")
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("txdaData(%q) => %v", cmd, err)
+	}
+
+	return b
+}
+
+func TestSendMessage(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	m := NewMessenger(im)
+	defer m.Close()
+
+	data := bytes.Repeat([]byte("0123456789"), 20)
+	serial.setRespData([]byte("OK\r\n"), []byte("OK\r\n"), []byte("OK\r\n"), []byte("OK\r\n"))
+	if err := m.SendMessage(context.Background(), data); err != nil {
+		t.Fatalf("SendMessage() => %v, want nil", err)
+	}
+
+	writes := serial.takeWritedAll()
+	if len(writes) != 4 {
+		t.Fatalf("SendMessage(%d bytes) => %d writes, want 4", len(data), len(writes))
+	}
+
+	var got []byte
+	for i, w := range writes {
+		f, ok := decodeFragment(txdaData(t, w))
+		if !ok || f.index != i || f.count != 4 {
+			t.Errorf("[%d]SendMessage() => fragment %+v, want index %d of 4", i, f, i)
+		}
+		got = append(got, f.data...)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("SendMessage() => %q, want %q", got, data)
+	}
+
+	if err := m.SendMessage(context.Background(), make([]byte, MaxMessageSize+1)); err == nil {
+		t.Errorf("SendMessage(%d bytes) => nil, want error", MaxMessageSize+1)
+	}
+}
+
+func TestReceiveMessage(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	m := NewMessenger(im, WithReassemblyTimeout(100*time.Millisecond))
+	defer m.Close()
+
+	frag := func(id Id, msgID uint16, index, count int, data string) string {
+		return rcvedLineOf(id, encodeFragment(fragment{msgID: msgID, index: index, count: count, data: []byte(data)}))
+	}
+
+	// out of order, interleaved with another sender, with a duplicate
+	// fragment and a plain packet that is not a fragment
+	serial.setDummyData([]byte(
+		frag(0x0001, 7, 1, 2, "world") +
+			frag(0x0002, 7, 0, 1, "other") +
+			rcvedLineOf(0x0001, []byte("plain")) +
+			frag(0x0001, 7, 1, 2, "world") +
+			frag(0x0001, 7, 0, 2, "hello ") +
+			frag(0x0001, 7, 0, 2, "hello ")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for _, want := range []struct {
+		id   Id
+		data string
+	}{{0x0002, "other"}, {0x0001, "hello world"}} {
+		msg, err := m.ReceiveMessage(ctx)
+		if err != nil || msg.FromId != want.id || string(msg.Data) != want.data {
+			t.Errorf("ReceiveMessage() => %+v, %v, want %q from %v", msg, err, want.data, want.id)
+		}
+	}
+
+	// a message whose last fragment arrives after the timeout is dropped,
+	// and a repeated complete message is suppressed
+	serial.setDummyData([]byte(frag(0x0001, 8, 0, 2, "late ")))
+	time.Sleep(300 * time.Millisecond)
+	serial.setDummyData([]byte(frag(0x0001, 8, 1, 2, "message") + frag(0x0001, 7, 0, 1, "again")))
+
+	ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if msg, err := m.ReceiveMessage(ctx); err != context.DeadlineExceeded {
+		t.Errorf("ReceiveMessage() => %+v, %v, want %v", msg, err, context.DeadlineExceeded)
+	}
+
+	m.Close()
+	if _, err := m.ReceiveMessage(context.Background()); err != ErrClosed {
+		t.Errorf("ReceiveMessage() after Close() => %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestSendReliable(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	m := NewMessenger(im, WithAckTimeout(100*time.Millisecond), WithRetries(2))
+	defer m.Close()
+
+	// the first transmission is answered by an ACK meant for another
+	// sender, the retransmission by ours
+	m.nextID = 9
+	serial.setRespData(
+		[]byte("0001\r\n"),
+		[]byte("OK\r\n"+rcvedLineOf(0x0002, encodeAck(10, 0x0003))),
+		[]byte("OK\r\n"+rcvedLineOf(0x0002, encodeAck(10, 0x0001))))
+
+	report, err := m.SendReliable(context.Background(), 0x0002, []byte("on"))
+	if err != nil || report.Attempts != 2 {
+		t.Errorf("SendReliable() => %+v, %v, want 2 attempts", report, err)
+	}
+
+	writes := serial.takeWritedAll()
+	if len(writes) != 3 {
+		t.Fatalf("SendReliable() => %d writes, want 3", len(writes))
+	}
+	f, ok := decodeFragment(txdaData(t, writes[2]))
+	if !ok || !f.reliable || f.msgID != 10 || string(f.data) != "on" {
+		t.Errorf("SendReliable() => fragment %+v, want reliable message 10", f)
+	}
+
+	serial.setRespData([]byte("OK\r\n"), []byte("OK\r\n"), []byte("OK\r\n"))
+	report, err = m.SendReliable(context.Background(), 0x0002, []byte("off"))
+	if !errors.Is(err, ErrNotAcknowledged) || report.Attempts != 3 {
+		t.Errorf("SendReliable() without ACK => %+v, %v, want 3 attempts and %v", report, err, ErrNotAcknowledged)
+	}
+}
+
+func TestReceiveReliable(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	m := NewMessenger(im)
+	defer m.Close()
+
+	line := rcvedLineOf(0x0002, encodeFragment(fragment{reliable: true, msgID: 5, index: 0, count: 1, data: []byte("on")}))
+	wantAck := encodeAck(5, 0x0002)
+
+	for i := 0; i < 2; i++ {
+		serial.setRespData([]byte("OK\r\n"))
+		serial.setDummyData([]byte(line))
+
+		var writes [][]byte
+		for deadline := time.Now().Add(time.Second); len(writes) == 0 && time.Now().Before(deadline); {
+			time.Sleep(10 * time.Millisecond)
+			writes = serial.takeWritedAll()
+		}
+		if len(writes) != 1 || !bytes.Equal(txdaData(t, writes[0]), wantAck) {
+			t.Errorf("[%d]reliable message => writes %q, want ACK %X", i, writes, wantAck)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if msg, err := m.ReceiveMessage(ctx); err != nil || string(msg.Data) != "on" {
+		t.Errorf("ReceiveMessage() => %+v, %v, want %q", msg, err, "on")
+	}
+	if msg, err := m.ReceiveMessage(ctx); err != context.DeadlineExceeded {
+		t.Errorf("ReceiveMessage() for a retransmission => %+v, %v, want %v", msg, err, context.DeadlineExceeded)
+	}
+}
+
+var MessengerOptionTests = []struct {
+	in_opts        []MessengerOption
+	out_timeout    time.Duration
+	out_retries    int
+	out_ackTimeout time.Duration
+}{
+	{[]MessengerOption{WithReassemblyTimeout(0), WithRetries(-1), WithAckTimeout(0)}, defaultReassemblyTimeout, 0, defaultAckTimeout},
+	{[]MessengerOption{WithReassemblyTimeout(-time.Second), WithAckTimeout(-time.Second)}, defaultReassemblyTimeout, defaultRetries, defaultAckTimeout},
+	// too short to halve into a ticker interval
+	{[]MessengerOption{WithReassemblyTimeout(1)}, 1, defaultRetries, defaultAckTimeout},
+}
+
+func TestMessengerOptions(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	for i, tt := range MessengerOptionTests {
+		m := NewMessenger(im, tt.in_opts...)
+		if m.timeout != tt.out_timeout || m.retries != tt.out_retries || m.ackTimeout != tt.out_ackTimeout {
+			t.Errorf("[%d]NewMessenger() => %v, %v, %v, want %v, %v, %v",
+				i, m.timeout, m.retries, m.ackTimeout, tt.out_timeout, tt.out_retries, tt.out_ackTimeout)
+		}
+		m.Close()
+	}
+}
+
+func TestAckFailures(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	m := NewMessenger(im)
+
+	// the module answers NG to the ACK's TXDA
+	serial.setRespData([]byte("NG\r\n"))
+	serial.setDummyData([]byte(rcvedLineOf(0x0002, encodeFragment(fragment{reliable: true, msgID: 7, index: 0, count: 1, data: []byte("on")}))))
+
+	for deadline := time.Now().Add(time.Second); m.AckFailures() == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := m.AckFailures(); n != 1 {
+		t.Errorf("AckFailures() => %v, want 1", n)
+	}
+
+	m.Close()
+	serial.takeWritedAll()
+	m.ack(8, 0x0002)
+	time.Sleep(50 * time.Millisecond)
+	if got := serial.takeWritedAll(); len(got) != 0 {
+		t.Errorf("ACK after Close() => wrote %q, want nothing", got)
+	}
+}
@@ -0,0 +1,95 @@
+package im920
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Packet is a single received line: the payload together with its
+// sender, the time it was framed and the line as received.
+type Packet struct {
+	ReadInfo
+	Data []byte
+	Time time.Time
+	Raw  string
+}
+
+type rcvedLine struct {
+	line   string
+	at     time.Time
+	charIO bool
+}
+
+// parsePacket parses a received line whose header is parsed by headers.
+// In character I/O mode the data is the text itself rather than comma
+// separated hex.
+func parsePacket(line string, charIO bool, headers func(string) (ReadInfo, error)) (pkt Packet, err error) {
+	pkt.Raw = line
+
+	strs := strings.SplitN(line, ":", 2)
+	if len(strs) < 2 {
+		err = &FrameError{Line: line, Err: errors.New("error: Split header and data failed")}
+		return
+	}
+
+	pkt.ReadInfo, err = headers(strs[0])
+	if err != nil {
+		err = &FrameError{Line: line, Err: fmt.Errorf("error: parseReadHeaders failed: %w", err)}
+		return
+	}
+
+	if charIO {
+		pkt.Data = []byte(strs[1])
+	} else {
+		dataStr := strings.Replace(strs[1], ",", "", -1)
+		pkt.Data, err = hex.DecodeString(dataStr)
+		if err != nil {
+			err = &FrameError{Line: line, Err: fmt.Errorf("error: Decode failed (%s): %w", dataStr, err)}
+			return
+		}
+	}
+	if len(pkt.Data) == 0 {
+		err = &FrameError{Line: line, Err: errors.New("error: Decode failed: no data")}
+		return
+	}
+
+	return
+}
+
+func (im *IM920) ReadPacket() (Packet, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), im.readTimeout)
+	defer cancel()
+
+	pkt, err := im.ReadPacketContext(ctx)
+	if err == context.DeadlineExceeded {
+		err = io.EOF
+	}
+
+	return pkt, err
+}
+
+// ReadPacketContext waits for a received packet until ctx is done.
+func (im *IM920) ReadPacketContext(ctx context.Context) (Packet, error) {
+	var r rcvedLine
+	select {
+	case r = <-im.rcvedData:
+	default:
+		select {
+		case r = <-im.rcvedData:
+		case <-im.readerDone:
+			return Packet{}, fmt.Errorf("error: Read failed: reader stopped: %w", im.readErr)
+		case <-ctx.Done():
+			return Packet{}, ctx.Err()
+		}
+	}
+
+	pkt, err := parsePacket(r.line, r.charIO, im.headers)
+	pkt.Time = r.at
+
+	return pkt, err
+}
@@ -0,0 +1,252 @@
+package im920
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const network = "im920"
+
+// Addr is the address of a module: its ID and node number.
+type Addr struct {
+	Id   Id
+	Node Node
+}
+
+func (a *Addr) Network() string {
+	return network
+}
+
+// String formats the ID as RDID prints it, e.g. "06E5".
+func (a *Addr) String() string {
+	return fmt.Sprintf("%04X", uint16(a.Id))
+}
+
+// NodeString formats a in the order the module prints a sender in a
+// received line, e.g. "00,06E5".
+func (a *Addr) NodeString() string {
+	return fmt.Sprintf("%02X,%04X", uint8(a.Node), uint16(a.Id))
+}
+
+// ParseAddr parses an ID formatted by Addr.String, such as "06E5", or an
+// address formatted by Addr.NodeString.
+func ParseAddr(s string) (*Addr, error) {
+	strs := strings.Split(s, ",")
+	if len(strs) == 1 {
+		strs = []string{"00", strs[0]}
+	}
+	if len(strs) != 2 {
+		return nil, fmt.Errorf("error: invalid address (%s)", s)
+	}
+
+	node, err := strToUint16(strs[0])
+	if err != nil {
+		return nil, fmt.Errorf("error: invalid node (%s): %w", strs[0], err)
+	}
+	if node > 0xff {
+		return nil, fmt.Errorf("error: invalid node (%s)", strs[0])
+	}
+	id, err := strToUint16(strs[1])
+	if err != nil {
+		return nil, fmt.Errorf("error: invalid ID (%s): %w", strs[1], err)
+	}
+
+	return &Addr{Id: Id(id), Node: Node(node)}, nil
+}
+
+// deadline is a settable point in time that wakes up waiters when it
+// changes, as net.Conn deadlines must apply to pending calls.
+type deadline struct {
+	m       sync.Mutex
+	t       time.Time
+	changed chan struct{}
+}
+
+func newDeadline() *deadline {
+	return &deadline{changed: make(chan struct{})}
+}
+
+func (d *deadline) set(t time.Time) {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	d.t = t
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
+
+func (d *deadline) get() (time.Time, <-chan struct{}) {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	return d.t, d.changed
+}
+
+// deadlineTimer returns a channel that fires at t, or never if t is zero.
+func deadlineTimer(t time.Time) (<-chan time.Time, func() bool) {
+	if t.IsZero() {
+		return nil, func() bool { return true }
+	}
+
+	timer := time.NewTimer(time.Until(t))
+
+	return timer.C, timer.Stop
+}
+
+// PacketConn adapts an IM920 to net.PacketConn. Each packet read is one
+// received line; each packet written is one TXDA. Since the IM920
+// broadcasts, the address given to WriteTo is only checked for its
+// type.
+type PacketConn struct {
+	im        *IM920
+	local     *Addr
+	pkts      <-chan Packet
+	cancel    func()
+	rd        *deadline
+	wd        *deadline
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+var _ net.PacketConn = (*PacketConn)(nil)
+
+// NewPacketConn returns a PacketConn on im. Closing it does not close im.
+func NewPacketConn(im *IM920) (*PacketConn, error) {
+	id, err := im.GetId()
+	if err != nil {
+		return nil, fmt.Errorf("error: GetId failed: %w", err)
+	}
+
+	pkts, cancel := im.Subscribe(maxRcvedData)
+
+	return &PacketConn{
+		im:     im,
+		local:  &Addr{Id: id},
+		pkts:   pkts,
+		cancel: cancel,
+		rd:     newDeadline(),
+		wd:     newDeadline(),
+		closed: make(chan struct{}),
+	}, nil
+}
+
+func (c *PacketConn) opError(op string, addr net.Addr, err error) error {
+	return &net.OpError{Op: op, Net: network, Source: c.local, Addr: addr, Err: err}
+}
+
+func (c *PacketConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	for {
+		t, changed := c.rd.get()
+		expired, stop := deadlineTimer(t)
+
+		select {
+		case pkt, ok := <-c.pkts:
+			stop()
+			if !ok {
+				return 0, nil, c.opError("read", nil, net.ErrClosed)
+			}
+			n = copy(p, pkt.Data)
+			return n, &Addr{Id: pkt.FromId, Node: pkt.FromNode}, nil
+		case <-c.closed:
+			stop()
+			return 0, nil, c.opError("read", nil, net.ErrClosed)
+		case <-expired:
+			return 0, nil, c.opError("read", nil, os.ErrDeadlineExceeded)
+		case <-changed:
+			stop()
+		}
+	}
+}
+
+func (c *PacketConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
+	if _, ok := addr.(*Addr); !ok {
+		return 0, c.opError("write", addr, fmt.Errorf("error: not an %s address (%v)", network, addr))
+	}
+	if len(p) > maxTXDA {
+		return 0, c.opError("write", addr, fmt.Errorf("error: packet too large (%d > %d)", len(p), maxTXDA))
+	}
+
+	select {
+	case <-c.closed:
+		return 0, c.opError("write", addr, net.ErrClosed)
+	default:
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// cancel the write when the deadline passes, moves or the conn closes
+	go func() {
+		for {
+			t, changed := c.wd.get()
+			expired, stop := deadlineTimer(t)
+			select {
+			case <-ctx.Done():
+				stop()
+				return
+			case <-c.closed:
+				stop()
+				cancel()
+				return
+			case <-expired:
+				cancel()
+				return
+			case <-changed:
+				stop()
+			}
+		}
+	}()
+
+	n, err = c.im.WriteContext(ctx, p)
+	if err != nil {
+		if ctx.Err() != nil {
+			select {
+			case <-c.closed:
+				err = net.ErrClosed
+			default:
+				err = os.ErrDeadlineExceeded
+			}
+		}
+		return n, c.opError("write", addr, err)
+	}
+
+	return
+}
+
+// Close stops receiving. It does not close the underlying IM920.
+func (c *PacketConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.cancel()
+	})
+
+	return nil
+}
+
+func (c *PacketConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *PacketConn) SetDeadline(t time.Time) error {
+	c.rd.set(t)
+	c.wd.set(t)
+
+	return nil
+}
+
+func (c *PacketConn) SetReadDeadline(t time.Time) error {
+	c.rd.set(t)
+
+	return nil
+}
+
+func (c *PacketConn) SetWriteDeadline(t time.Time) error {
+	c.wd.set(t)
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package im920
+
+import (
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+var AddrTests = []struct {
+	in             string
+	out            Addr
+	out_string     string
+	out_errorIsNil bool
+}{
+	{"00,06E5", Addr{Id: 0x06E5}, "06E5", true},
+	{"01,06E6", Addr{Id: 0x06E6, Node: 0x01}, "06E6", true},
+	{"06E5", Addr{Id: 0x06E5}, "06E5", true},
+	{"100,06E5", Addr{}, "", false},
+	{"00,ZZZZ", Addr{}, "", false},
+	{"00,06E5,B5", Addr{}, "", false},
+}
+
+func TestParseAddr(t *testing.T) {
+	for i, tt := range AddrTests {
+		addr, err := ParseAddr(tt.in)
+		if (err == nil) != tt.out_errorIsNil {
+			t.Errorf("[%d]ParseAddr(%q) => %v, want errorIsNil %v", i, tt.in, err, tt.out_errorIsNil)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if *addr != tt.out {
+			t.Errorf("[%d]ParseAddr(%q) => %+v, want %+v", i, tt.in, *addr, tt.out)
+		}
+		if addr.String() != tt.out_string {
+			t.Errorf("[%d]String() => %q, want %q", i, addr.String(), tt.out_string)
+		}
+		if back, err := ParseAddr(addr.String()); err != nil || back.Id != addr.Id {
+			t.Errorf("[%d]ParseAddr(%q) => %v, %v, want ID %04X", i, addr.String(), back, err, uint16(addr.Id))
+		}
+		if back, err := ParseAddr(addr.NodeString()); err != nil || *back != *addr {
+			t.Errorf("[%d]ParseAddr(%q) => %v, %v, want %+v", i, addr.NodeString(), back, err, *addr)
+		}
+	}
+}
+
+func TestPacketConn(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	serial.setRespData([]byte("06E5\r\n"))
+	c, err := NewPacketConn(im)
+	if err != nil {
+		t.Fatalf("NewPacketConn() => %v, want nil", err)
+	}
+	defer c.Close()
+
+	if c.LocalAddr().String() != "06E5" || c.LocalAddr().Network() != "im920" {
+		t.Errorf("LocalAddr() => %v, want 06E5", c.LocalAddr())
+	}
+
+	serial.setDummyData([]byte(rcvedLineOf(0x06E6, []byte("hello"))))
+	buf := make([]byte, 64)
+	n, addr, err := c.ReadFrom(buf)
+	if err != nil || string(buf[:n]) != "hello" {
+		t.Fatalf("ReadFrom() => %q, %v, want %q, nil", buf[:n], err, "hello")
+	}
+	if addr.String() != "06E6" {
+		t.Errorf("ReadFrom() => addr %v, want 06E6", addr)
+	}
+
+	serial.setRespData([]byte("OK\r\n"))
+	n, err = c.WriteTo([]byte("hi"), addr)
+	if err != nil || n != 2 {
+		t.Errorf("WriteTo() => %v, %v, want 2, nil", n, err)
+	}
+	if got := txdaData(t, serial.getWritedData()); string(got) != "hi" {
+		t.Errorf("WriteTo() => wrote %q, want %q", got, "hi")
+	}
+
+	if _, err := c.WriteTo(make([]byte, maxTXDA+1), addr); err == nil {
+		t.Errorf("WriteTo(%d bytes) => nil, want error", maxTXDA+1)
+	}
+	if _, err := c.WriteTo([]byte("hi"), &net.UDPAddr{}); err == nil {
+		t.Errorf("WriteTo(UDPAddr) => nil, want error")
+	}
+}
+
+func TestPacketConnDeadline(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	serial.setRespData([]byte("06E5\r\n"))
+	c, err := NewPacketConn(im)
+	if err != nil {
+		t.Fatalf("NewPacketConn() => %v, want nil", err)
+	}
+
+	c.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	_, _, err = c.ReadFrom(make([]byte, 64))
+	var nerr net.Error
+	if !errors.Is(err, os.ErrDeadlineExceeded) || !errors.As(err, &nerr) || !nerr.Timeout() {
+		t.Errorf("ReadFrom() after deadline => %v, want timeout", err)
+	}
+
+	// moving the deadline applies to a pending ReadFrom
+	c.SetReadDeadline(time.Time{})
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := c.ReadFrom(make([]byte, 64))
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	c.SetReadDeadline(time.Now())
+	select {
+	case err := <-done:
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("ReadFrom() => %v, want %v", err, os.ErrDeadlineExceeded)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("ReadFrom() did not return after SetReadDeadline()")
+	}
+
+	c.SetReadDeadline(time.Time{})
+	go func() {
+		_, _, err := c.ReadFrom(make([]byte, 64))
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	c.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Errorf("ReadFrom() => %v, want %v", err, net.ErrClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("ReadFrom() did not return after Close()")
+	}
+}
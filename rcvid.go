@@ -0,0 +1,128 @@
+package im920
+
+import (
+	"context"
+	"fmt"
+)
+
+const maxRcvIds = 8
+
+// SetRcvIds makes the registered receive IDs exactly ids. IDs are only
+// ever added, unless one has to go; then the list is erased and written
+// again, inside a single write-enable window either way.
+func (im *IM920) SetRcvIds(ctx context.Context, ids []Id) error {
+	if err := checkRcvIds(ids); err != nil {
+		return err
+	}
+
+	cur, err := im.GetAllRcvIdContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return im.syncRcvIds(ctx, cur, ids)
+}
+
+func (im *IM920) RemoveRcvId(id Id) error {
+	return im.RemoveRcvIdContext(context.Background(), id)
+}
+
+// RemoveRcvIdContext unregisters id. Since the module can only erase all
+// IDs, the others are registered again.
+func (im *IM920) RemoveRcvIdContext(ctx context.Context, id Id) error {
+	cur, err := im.GetAllRcvIdContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var ids []Id
+	for _, v := range cur {
+		if v != id {
+			ids = append(ids, v)
+		}
+	}
+
+	return im.syncRcvIds(ctx, cur, ids)
+}
+
+func (im *IM920) syncRcvIds(ctx context.Context, cur, ids []Id) error {
+	erase, add := rcvIdChanges(cur, ids)
+	if !erase && len(add) == 0 {
+		return nil
+	}
+
+	return im.writeEnable(ctx, true, func() error {
+		return im.setRcvIds(ctx, erase, add)
+	})
+}
+
+func checkRcvIds(ids []Id) error {
+	var unique []Id
+	for _, id := range ids {
+		if !contains(unique, id) {
+			unique = append(unique, id)
+		}
+	}
+
+	if len(unique) > maxRcvIds {
+		return fmt.Errorf("error: too many receive IDs (%d > %d)", len(unique), maxRcvIds)
+	}
+
+	return nil
+}
+
+// rcvIdChanges works out how to get from the registered IDs cur to want.
+// The module can only add an ID or erase them all, so unless want only
+// adds to cur, everything is erased and want registered afresh.
+func rcvIdChanges(cur, want []Id) (erase bool, ids []Id) {
+	registered := make(map[Id]bool)
+	for _, id := range cur {
+		registered[id] = true
+	}
+	wanted := make(map[Id]bool)
+	for _, id := range want {
+		wanted[id] = true
+	}
+
+	for id := range registered {
+		if !wanted[id] {
+			erase = true
+		}
+	}
+
+	for _, id := range want {
+		if (erase || !registered[id]) && !contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	return
+}
+
+func contains(ids []Id, id Id) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setRcvIds registers ids, after erasing all registered IDs if erase is
+// set. The caller must have issued ENWR.
+func (im *IM920) setRcvIds(ctx context.Context, erase bool, ids []Id) error {
+	if erase {
+		if err := im.deleteAllRcvId(ctx); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range ids {
+		if err := im.addRcvId(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
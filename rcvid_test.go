@@ -0,0 +1,111 @@
+package im920
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+var SetRcvIdsTests = []struct {
+	in_ids         []Id
+	in_respData    [][]byte
+	out_cmds       []string
+	out_errorIsNil bool
+}{
+	{
+		// already registered
+		[]Id{0x0002, 0x0001, 0x0001},
+		[][]byte{[]byte("0001\r\n0002\r\n")},
+		[]string{"RRID \r\n"}, true,
+	},
+	{
+		[]Id{0x0001, 0x0002, 0x0003},
+		[][]byte{[]byte("0001\r\n0002\r\n"), []byte("OK\r\n"), []byte("OK\r\n"), []byte("OK\r\n")},
+		[]string{"RRID \r\n", "ENWR \r\n", "SRID 0003\r\n", "DSWR \r\n"}, true,
+	},
+	{
+		[]Id{0x0002},
+		[][]byte{[]byte("0001\r\n0002\r\n"), []byte("OK\r\n"), []byte("OK\r\n"), []byte("OK\r\n"), []byte("OK\r\n")},
+		[]string{"RRID \r\n", "ENWR \r\n", "ERID \r\n", "SRID 0002\r\n", "DSWR \r\n"}, true,
+	},
+	{
+		[]Id{},
+		[][]byte{[]byte("0001\r\n"), []byte("OK\r\n"), []byte("OK\r\n"), []byte("OK\r\n")},
+		[]string{"RRID \r\n", "ENWR \r\n", "ERID \r\n", "DSWR \r\n"}, true,
+	},
+	{
+		// DSWR is issued even though ERID fails
+		[]Id{0x0003},
+		[][]byte{[]byte("0001\r\n"), []byte("OK\r\n"), []byte("NG\r\n"), []byte("OK\r\n")},
+		[]string{"RRID \r\n", "ENWR \r\n", "ERID \r\n", "DSWR \r\n"}, false,
+	},
+	{
+		[]Id{1, 2, 3, 4, 5, 6, 7, 8, 9},
+		nil,
+		nil, false,
+	},
+}
+
+func TestSetRcvIds(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	for i, tt := range SetRcvIdsTests {
+		serial.takeWritedAll()
+		serial.setRespData(tt.in_respData...)
+		err := im.SetRcvIds(context.Background(), tt.in_ids)
+		if (err == nil) != tt.out_errorIsNil {
+			t.Errorf("[%d]SetRcvIds() => %v, want errorIsNil = %v", i, err, tt.out_errorIsNil)
+		}
+
+		got := writesOf(serial.takeWritedAll())
+		if len(got) == 0 {
+			got = nil
+		}
+		if !reflect.DeepEqual(got, tt.out_cmds) {
+			t.Errorf("[%d]SetRcvIds() => wrote %q, want %q", i, got, tt.out_cmds)
+		}
+	}
+}
+
+var RemoveRcvIdTests = []struct {
+	in             Id
+	in_respData    [][]byte
+	out_cmds       []string
+	out_errorIsNil bool
+}{
+	{
+		0x0003,
+		[][]byte{[]byte("0001\r\n0002\r\n")},
+		[]string{"RRID \r\n"}, true,
+	},
+	{
+		0x0001,
+		[][]byte{[]byte("0001\r\n0002\r\n"), []byte("OK\r\n"), []byte("OK\r\n"), []byte("OK\r\n"), []byte("OK\r\n")},
+		[]string{"RRID \r\n", "ENWR \r\n", "ERID \r\n", "SRID 0002\r\n", "DSWR \r\n"}, true,
+	},
+}
+
+func TestRemoveRcvId(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	for i, tt := range RemoveRcvIdTests {
+		serial.takeWritedAll()
+		serial.setRespData(tt.in_respData...)
+		err := im.RemoveRcvId(tt.in)
+		if (err == nil) != tt.out_errorIsNil {
+			t.Errorf("[%d]RemoveRcvId() => %v, want errorIsNil = %v", i, err, tt.out_errorIsNil)
+		}
+
+		got := writesOf(serial.takeWritedAll())
+		if len(got) == 0 {
+			got = nil
+		}
+		if !reflect.DeepEqual(got, tt.out_cmds) {
+			t.Errorf("[%d]RemoveRcvId() => wrote %q, want %q", i, got, tt.out_cmds)
+		}
+	}
+}
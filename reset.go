@@ -0,0 +1,142 @@
+package im920
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	bootTimeout  = 3 * time.Second
+	bootInterval = 50 * time.Millisecond
+)
+
+// State is the configuration read back from the module after a reset.
+type State struct {
+	Id   Id
+	Ch   Ch
+	Mode Mode
+}
+
+// Reset restarts the module (SRST), waits until it answers again and
+// reads back its ID, channel and communication mode. Settings that were
+// not written with persist are lost. The driver follows the character
+// I/O and ACK modes the module reports in RPRM; without RPRM it falls
+// back to the modes last set with persist.
+func (im *IM920) Reset(ctx context.Context) (State, error) {
+	return im.reset(ctx, "SRST")
+}
+
+// FactoryReset clears every parameter to its factory default (PCLR),
+// including the baud rate and character I/O mode, and then does what
+// Reset does.
+func (im *IM920) FactoryReset(ctx context.Context) (State, error) {
+	return im.reset(ctx, "PCLR")
+}
+
+func (im *IM920) reset(ctx context.Context, cmd string) (state State, err error) {
+	if err = im.restart(ctx, cmd); err != nil {
+		return
+	}
+
+	if state.Id, err = im.GetIdContext(ctx); err != nil {
+		return
+	}
+	if state.Ch, err = im.GetChContext(ctx); err != nil {
+		return
+	}
+	state.Mode, err = im.GetCommModeContext(ctx)
+
+	return
+}
+
+// restart issues cmd and waits for the module to boot, then drops
+// whatever was received before.
+func (im *IM920) restart(ctx context.Context, cmd string) error {
+	if err := im.lock(ctx); err != nil {
+		return err
+	}
+	defer im.unlock()
+
+	if err := im.awake(ctx); err != nil {
+		return err
+	}
+
+	// the module may restart before it gets to answer
+	if err := im.issueOK(ctx, cmd, ""); err != nil && !errors.Is(err, ErrNoResponse) {
+		return fmt.Errorf("error: %s failed: %w", cmd, err)
+	}
+	im.asleep = false
+
+	if cmd == "PCLR" {
+		im.saveCharIO(false)
+		im.saveAck(false)
+
+		if im.BaudRate() != defaultBps && im.reopen != nil {
+			if err := im.reopenPort(defaultBps); err != nil {
+				return err
+			}
+		}
+	}
+
+	bctx, cancel := context.WithTimeout(ctx, bootTimeout)
+	defer cancel()
+
+	for {
+		timer := time.NewTimer(bootInterval)
+		select {
+		case <-bctx.Done():
+			timer.Stop()
+			return fmt.Errorf("error: no response after %s: %w", cmd, bctx.Err())
+		case <-timer.C:
+		}
+
+		pctx, pcancel := context.WithTimeout(bctx, probeTimeout)
+		err := im.confirmLink(pctx)
+		pcancel()
+		if err == nil {
+			break
+		}
+	}
+
+	im.flushResponse()
+	im.flushRcvedData()
+
+	var s Settings
+	have := make(map[string]bool)
+	if resp, err := im.issue(ctx, "RPRM", ""); err == nil {
+		have = parseParams(string(resp), &s)
+	}
+	im.restoreModes(s, have)
+
+	return nil
+}
+
+// restoreModes makes the driver's modes those the module booted in:
+// what RPRM reported in s, or else what was last saved.
+func (im *IM920) restoreModes(s Settings, have map[string]bool) {
+	im.charIOM.Lock()
+	if have["CIO"] {
+		im.charIOSaved = s.CharIO
+	}
+	im.charIO = im.charIOSaved
+	im.charIOM.Unlock()
+
+	im.ackM.Lock()
+	if have["ACK"] {
+		im.ackSaved = s.Ack
+	}
+	im.ack = im.ackSaved
+	im.ackM.Unlock()
+}
+
+func (im *IM920) flushRcvedData() {
+	for {
+		select {
+		case <-im.rcvedData:
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package im920
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReset(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	// a packet received before the reset is stale afterwards
+	serial.setDummyData([]byte("00,06E5,B5:0A\r\n"))
+	time.Sleep(50 * time.Millisecond)
+
+	serial.setRespData([]byte("OK\r\n"), []byte("06E5\r\n"), []byte("NG\r\n"), []byte("06E5\r\n"), []byte("01\r\n"), []byte("02\r\n"))
+	state, err := im.Reset(context.Background())
+	if err != nil {
+		t.Fatalf("Reset() => %v, want nil", err)
+	}
+	if want := (State{Id: 0x06E5, Ch: 0x01, Mode: LONG_MODE}); state != want {
+		t.Errorf("Reset() => %+v, want %+v", state, want)
+	}
+	want := []string{"SRST \r\n", "RDID \r\n", "RPRM \r\n", "RDID \r\n", "RDCH \r\n", "RDRT \r\n"}
+	if got := writesOf(serial.takeWritedAll()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Reset() => wrote %q, want %q", got, want)
+	}
+
+	if _, err := im.ReadPacket(); err != io.EOF {
+		t.Errorf("ReadPacket() after Reset() => %v, want %v", err, io.EOF)
+	}
+}
+
+var ResetModesTests = []struct {
+	in_persist bool
+	in_rprm    []byte
+	out_charIO bool
+}{
+	// no RPRM: a mode set without persist is gone
+	{false, []byte("NG\r\n"), false},
+	{true, []byte("NG\r\n"), true},
+	// RPRM tells
+	{false, []byte("ID:06E5\r\nECIO\r\n"), true},
+	{true, []byte("ID:06E5\r\nDCIO\r\n"), false},
+}
+
+func TestResetModes(t *testing.T) {
+	for i, tt := range ResetModesTests {
+		serial := newFakeSerial()
+		im := newTestIM920(serial)
+
+		resp := [][]byte{[]byte("OK\r\n")}
+		if tt.in_persist {
+			resp = append(resp, []byte("OK\r\n"), []byte("OK\r\n"))
+		}
+		serial.setRespData(resp...)
+		if err := im.SetCharIOMode(true, tt.in_persist); err != nil {
+			t.Fatalf("[%d]SetCharIOMode(true, %v) => %v, want nil", i, tt.in_persist, err)
+		}
+
+		serial.setRespData([]byte("OK\r\n"), []byte("06E5\r\n"), tt.in_rprm, []byte("06E5\r\n"), []byte("01\r\n"), []byte("01\r\n"))
+		if _, err := im.Reset(context.Background()); err != nil {
+			t.Fatalf("[%d]Reset() => %v, want nil", i, err)
+		}
+		if im.GetCharIOMode() != tt.out_charIO {
+			t.Errorf("[%d]GetCharIOMode() after Reset() => %v, want %v", i, im.GetCharIOMode(), tt.out_charIO)
+		}
+
+		im.Close()
+	}
+}
+
+func TestFactoryReset(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	serial.setRespData([]byte("OK\r\n"))
+	if err := im.SetCharIOMode(true, false); err != nil {
+		t.Fatalf("SetCharIOMode() => %v, want nil", err)
+	}
+
+	// no answer to PCLR, then one ignored probe while the module boots
+	serial.setRespData([]byte{}, []byte{}, []byte("06E5\r\n"), []byte("NG\r\n"), []byte("06E5\r\n"), []byte("01\r\n"), []byte("01\r\n"))
+	state, err := im.FactoryReset(context.Background())
+	if err != nil {
+		t.Fatalf("FactoryReset() => %v, want nil", err)
+	}
+	if want := (State{Id: 0x06E5, Ch: 0x01, Mode: FAST_MODE}); state != want {
+		t.Errorf("FactoryReset() => %+v, want %+v", state, want)
+	}
+	if im.GetCharIOMode() {
+		t.Errorf("GetCharIOMode() after FactoryReset() => true, want false")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if _, err := im.Reset(ctx); err == nil {
+		t.Errorf("Reset() without answer => nil, want error")
+	}
+}
@@ -0,0 +1,71 @@
+package im920
+
+import (
+	"context"
+	"fmt"
+)
+
+type DestKind uint8
+
+const (
+	DEST_BROADCAST DestKind = iota
+	DEST_UNICAST
+	DEST_GROUP
+)
+
+func (k DestKind) String() string {
+	switch k {
+	case DEST_BROADCAST:
+		return "broadcast"
+	case DEST_UNICAST:
+		return "unicast"
+	case DEST_GROUP:
+		return "group"
+	}
+
+	return fmt.Sprintf("DestKind(%d)", uint8(k))
+}
+
+// Dest says where Send delivers a packet. To is the destination of
+// DEST_UNICAST and Group that of DEST_GROUP.
+type Dest struct {
+	Kind  DestKind
+	To    SLNode
+	Group Group
+}
+
+func Broadcast() Dest {
+	return Dest{Kind: DEST_BROADCAST}
+}
+
+func Unicast(to SLNode) Dest {
+	return Dest{Kind: DEST_UNICAST, To: to}
+}
+
+func ToGroup(group Group) Dest {
+	return Dest{Kind: DEST_GROUP, Group: group}
+}
+
+// Sender is the send API common to the IM920 and the IM920sL.
+type Sender interface {
+	// Send transmits p, at most 64 bytes, to to in a single packet.
+	Send(ctx context.Context, to Dest, p []byte) error
+}
+
+var _ Sender = (*IM920)(nil)
+
+// Send transmits p with TXDA. The IM920 cannot address a packet: every
+// module that registered this one's ID receives it, so only broadcast
+// is supported.
+func (im *IM920) Send(ctx context.Context, to Dest, p []byte) error {
+	if to.Kind != DEST_BROADCAST {
+		return fmt.Errorf("%w: %s send on IM920", ErrUnsupported, to.Kind)
+	}
+	if len(p) > maxTXDA {
+		return fmt.Errorf("error: too much data (%d > %d bytes)", len(p), maxTXDA)
+	}
+
+	_, err := im.WriteContext(ctx, p)
+
+	return err
+}
@@ -0,0 +1,180 @@
+package im920
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Settings is a snapshot of the module's parameters.
+type Settings struct {
+	Id      Id
+	Node    Node
+	Ch      Ch
+	Mode    Mode
+	Power   Power
+	Baud    int
+	CharIO  bool
+	Ack     bool
+	RcvIds  []Id
+	Version string
+}
+
+func (im *IM920) ReadSettings() (Settings, error) {
+	return im.ReadSettingsContext(context.Background())
+}
+
+// ReadSettingsContext reads every parameter with a single RPRM where the
+// firmware supports it, and queries whatever RPRM did not list one by
+// one. The module cannot report its character I/O and ACK modes except
+// through RPRM, so otherwise CharIO and Ack are what the driver tracks;
+// when RPRM does report them, the driver follows the module.
+func (im *IM920) ReadSettingsContext(ctx context.Context) (s Settings, err error) {
+	s.Baud = im.BaudRate()
+	s.CharIO = im.isCharIO()
+	s.Ack = im.GetAckMode()
+
+	have := make(map[string]bool)
+	resp, rerr := im.IssueCommandRespStrContext(ctx, "RPRM", "")
+	if rerr == nil {
+		have = parseParams(resp, &s)
+		if have["CIO"] {
+			// the driver must encode and decode as the module does
+			im.setCharIO(s.CharIO)
+		}
+		if have["ACK"] {
+			im.setAck(s.Ack)
+		}
+	} else if ctx.Err() != nil {
+		return s, fmt.Errorf("error: RPRM failed: %w", rerr)
+	}
+
+	if !have["ID"] {
+		if s.Id, err = im.GetIdContext(ctx); err != nil {
+			return
+		}
+	}
+	if !have["NN"] {
+		if s.Node, err = im.GetNodeContext(ctx); err != nil {
+			return
+		}
+	}
+	if !have["CH"] {
+		if s.Ch, err = im.GetChContext(ctx); err != nil {
+			return
+		}
+	}
+	if !have["RT"] {
+		if s.Mode, err = im.GetCommModeContext(ctx); err != nil {
+			return
+		}
+	}
+	if !have["PO"] {
+		if s.Power, err = im.GetTxPowerContext(ctx); err != nil {
+			return
+		}
+	}
+	if !have["RID"] {
+		if s.RcvIds, err = im.GetAllRcvIdContext(ctx); err != nil {
+			return
+		}
+	}
+	if !have["VR"] {
+		if s.Version, err = im.IssueCommandRespStrContext(ctx, "RDVR", ""); err != nil {
+			err = fmt.Errorf("error: RDVR failed: %w", err)
+			return
+		}
+	}
+	if v, verr := parseVersion(s.Version); verr == nil {
+		im.setVersion(v)
+	}
+
+	return
+}
+
+// parseParams fills s from an RPRM dump of "NAME:VALUE" lines, named
+// after the command that sets or reads the parameter, and reports which
+// parameters it found. Lines it does not understand are skipped.
+func parseParams(resp string, s *Settings) map[string]bool {
+	have := make(map[string]bool)
+
+	for _, line := range strings.Split(resp, "\r\n") {
+		name, value, ok := strings.Cut(line, ":")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		if !ok {
+			// character I/O and ACK are listed by the command that sets them
+			switch name {
+			case "ECIO":
+				s.CharIO = true
+				have["CIO"] = true
+			case "DCIO":
+				s.CharIO = false
+				have["CIO"] = true
+			case "ECAK":
+				s.Ack = true
+				have["ACK"] = true
+			case "DCAK":
+				s.Ack = false
+				have["ACK"] = true
+			}
+			continue
+		}
+
+		switch name {
+		case "ID", "RDID":
+			if v, err := strToUint16(value); err == nil {
+				s.Id = Id(v)
+				have["ID"] = true
+			}
+		case "STNN", "RDNN":
+			if v, err := strToUint16(value); err == nil {
+				s.Node = Node(v)
+				have["NN"] = true
+			}
+		case "STCH", "RDCH":
+			if v, err := strToUint16(value); err == nil {
+				s.Ch = Ch(v)
+				have["CH"] = true
+			}
+		case "STRT", "RDRT":
+			if v, err := strToUint16(value); err == nil {
+				s.Mode = Mode(v)
+				have["RT"] = true
+			}
+		case "STPO", "RDPO":
+			if v, err := strconv.Atoi(value); err == nil {
+				s.Power = Power(v)
+				have["PO"] = true
+			}
+		case "SBRT":
+			if v, err := strconv.Atoi(value); err == nil && v >= 0 && v < len(baudRates) {
+				s.Baud = baudRates[v]
+			}
+		case "SRID", "RRID":
+			ids, ok := parseIds(value)
+			if ok {
+				s.RcvIds = ids
+				have["RID"] = true
+			}
+		case "RDVR", "VER":
+			s.Version = value
+			have["VR"] = true
+		}
+	}
+
+	return have
+}
+
+func parseIds(s string) (ids []Id, ok bool) {
+	for _, v := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		id, err := strToUint16(v)
+		if err != nil {
+			return nil, false
+		}
+		ids = append(ids, Id(id))
+	}
+
+	return ids, true
+}
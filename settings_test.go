@@ -0,0 +1,74 @@
+package im920
+
+import (
+	"reflect"
+	"testing"
+)
+
+var ReadSettingsTests = []struct {
+	in_respData [][]byte
+	out         Settings
+	out_cmds    []string
+}{
+	{
+		// no RPRM on this firmware
+		[][]byte{
+			[]byte("NG\r\n"), []byte("06E5\r\n"), []byte("01\r\n"), []byte("05\r\n"), []byte("02\r\n"),
+			[]byte("3\r\n"), []byte("0001\r\n0002\r\n"), []byte("IM920 Ver.04.00\r\n"),
+		},
+		Settings{
+			Id: 0x06E5, Node: 0x01, Ch: 0x05, Mode: LONG_MODE, Power: POWER_10DBM, Baud: 19200,
+			RcvIds: []Id{0x0001, 0x0002}, Version: "IM920 Ver.04.00",
+		},
+		[]string{"RPRM \r\n", "RDID \r\n", "RDNN \r\n", "RDCH \r\n", "RDRT \r\n", "RDPO \r\n", "RRID \r\n", "RDVR \r\n"},
+	},
+	{
+		[][]byte{
+			[]byte("ID:06E5\r\nSTNN:01\r\nSTCH:05\r\nSTRT:1\r\nSTPO:2\r\nSBRT:5\r\nECIO\r\nECAK\r\nSRID:0001,0002\r\nRDVR:IM920 Ver.04.00\r\n"),
+		},
+		Settings{
+			Id: 0x06E5, Node: 0x01, Ch: 0x05, Mode: FAST_MODE, Power: POWER_0DBM, Baud: 38400, CharIO: true, Ack: true,
+			RcvIds: []Id{0x0001, 0x0002}, Version: "IM920 Ver.04.00",
+		},
+		[]string{"RPRM \r\n"},
+	},
+	{
+		// a partial dump is completed by individual queries; the
+		// character I/O and ACK modes are still the ones the last dump
+		// reported
+		[][]byte{
+			[]byte("ID:06E5\r\nSTCH:05\r\nUNKNOWN\r\n"), []byte("01\r\n"), []byte("02\r\n"),
+			[]byte("1\r\n"), []byte("0001\r\n"), []byte("IM920 Ver.04.00\r\n"),
+		},
+		Settings{
+			Id: 0x06E5, Node: 0x01, Ch: 0x05, Mode: LONG_MODE, Power: POWER_MINUS_10DBM, Baud: 19200, CharIO: true, Ack: true,
+			RcvIds: []Id{0x0001}, Version: "IM920 Ver.04.00",
+		},
+		[]string{"RPRM \r\n", "RDNN \r\n", "RDRT \r\n", "RDPO \r\n", "RRID \r\n", "RDVR \r\n"},
+	},
+}
+
+func TestReadSettings(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	for i, tt := range ReadSettingsTests {
+		serial.takeWritedAll()
+		serial.setRespData(tt.in_respData...)
+		s, err := im.ReadSettings()
+		if err != nil {
+			t.Errorf("[%d]ReadSettings() => %v, want nil", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(s, tt.out) {
+			t.Errorf("[%d]ReadSettings() => %+v, want %+v", i, s, tt.out)
+		}
+		if got := writesOf(serial.takeWritedAll()); !reflect.DeepEqual(got, tt.out_cmds) {
+			t.Errorf("[%d]ReadSettings() => wrote %q, want %q", i, got, tt.out_cmds)
+		}
+		if im.GetCharIOMode() != s.CharIO {
+			t.Errorf("[%d]GetCharIOMode() after ReadSettings() => %v, want %v", i, im.GetCharIOMode(), s.CharIO)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package im920
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	wakeDelay   = 10 * time.Millisecond
+	wakeRetries = 3
+)
+
+// WithAutoWake makes commands issued while the module sleeps wake it
+// first instead of failing with ErrAsleep.
+func WithAutoWake(enabled bool) Option {
+	return func(im *IM920) {
+		im.autoWake = enabled
+	}
+}
+
+// Sleep disables the receiver (DSRX), which puts the module into its low
+// power state. Until Wake, commands fail with ErrAsleep unless
+// WithAutoWake is set.
+func (im *IM920) Sleep() error {
+	return im.SleepContext(context.Background())
+}
+
+func (im *IM920) SleepContext(ctx context.Context) error {
+	if err := im.lock(ctx); err != nil {
+		return err
+	}
+	defer im.unlock()
+
+	if im.asleep {
+		return nil
+	}
+
+	if err := im.issueOK(ctx, "DSRX", ""); err != nil {
+		return fmt.Errorf("error: DSRX failed: %w", err)
+	}
+	im.asleep = true
+
+	return nil
+}
+
+// Wake wakes the module up and waits until it accepts commands again.
+func (im *IM920) Wake(ctx context.Context) error {
+	if err := im.lock(ctx); err != nil {
+		return err
+	}
+	defer im.unlock()
+
+	return im.wake(ctx)
+}
+
+// SetReceiveEnabled turns the receiver on or off. On the IM920 the
+// receiver is off exactly while the module sleeps, so this is Wake or
+// Sleep.
+func (im *IM920) SetReceiveEnabled(enabled bool) error {
+	return im.SetReceiveEnabledContext(context.Background(), enabled)
+}
+
+func (im *IM920) SetReceiveEnabledContext(ctx context.Context, enabled bool) error {
+	if enabled {
+		return im.Wake(ctx)
+	}
+
+	return im.SleepContext(ctx)
+}
+
+// awake makes sure the module is awake before a command. The caller
+// must hold the command lock.
+func (im *IM920) awake(ctx context.Context) error {
+	if !im.asleep {
+		return nil
+	}
+	if !im.autoWake {
+		return ErrAsleep
+	}
+
+	return im.wake(ctx)
+}
+
+// wake sends a byte to wake the UART of the module, then enables the
+// receiver. The first command after the wake-up byte may be lost, so
+// ENRX is retried. The caller must hold the command lock.
+func (im *IM920) wake(ctx context.Context) (err error) {
+	if !im.asleep {
+		return nil
+	}
+
+	s, _ := im.port()
+	if _, werr := s.Write([]byte("\r\n")); werr != nil {
+		return fmt.Errorf("error: Write failed: %w", werr)
+	}
+
+	timer := time.NewTimer(wakeDelay)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return ctx.Err()
+	case <-timer.C:
+	}
+
+	for i := 0; i < wakeRetries; i++ {
+		tctx, cancel := context.WithTimeout(ctx, probeTimeout)
+		err = im.issueOK(tctx, "ENRX", "")
+		cancel()
+		if err == nil {
+			im.asleep = false
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	return fmt.Errorf("error: ENRX failed: %w", err)
+}
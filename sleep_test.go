@@ -0,0 +1,83 @@
+package im920
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSleep(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	serial.setRespData([]byte("OK\r\n"))
+	if err := im.Sleep(); err != nil {
+		t.Fatalf("Sleep() => %v, want nil", err)
+	}
+	if got := serial.takeWritedAll(); len(got) != 1 || string(got[0]) != "DSRX \r\n" {
+		t.Errorf("Sleep() => wrote %q, want DSRX", got)
+	}
+
+	if _, err := im.Write([]byte{0x01}); !errors.Is(err, ErrAsleep) {
+		t.Errorf("Write() while asleep => %v, want %v", err, ErrAsleep)
+	}
+	if got := serial.takeWritedAll(); len(got) != 0 {
+		t.Errorf("Write() while asleep => wrote %q, want nothing", got)
+	}
+
+	serial.setRespData([]byte{}, []byte("OK\r\n"))
+	if err := im.SetReceiveEnabled(true); err != nil {
+		t.Fatalf("SetReceiveEnabled(true) => %v, want nil", err)
+	}
+	want := []string{"\r\n", "ENRX \r\n"}
+	if got := writesOf(serial.takeWritedAll()); !reflect.DeepEqual(got, want) {
+		t.Errorf("SetReceiveEnabled(true) => wrote %q, want %q", got, want)
+	}
+
+	serial.setRespData([]byte("OK\r\n"))
+	if _, err := im.Write([]byte{0x01}); err != nil {
+		t.Errorf("Write() after Wake() => %v, want nil", err)
+	}
+}
+
+func TestAutoWake(t *testing.T) {
+	serial := newFakeSerial()
+	im := New(serial, WithReadTimeout(100*time.Millisecond), WithAutoWake(true))
+	defer im.Close()
+
+	serial.setRespData([]byte("OK\r\n"))
+	if err := im.SetReceiveEnabled(false); err != nil {
+		t.Fatalf("SetReceiveEnabled(false) => %v, want nil", err)
+	}
+	serial.takeWritedAll()
+
+	serial.setRespData([]byte{}, []byte("OK\r\n"), []byte("OK\r\n"))
+	if _, err := im.Write([]byte{0x01}); err != nil {
+		t.Errorf("Write() while asleep => %v, want nil", err)
+	}
+	want := []string{"\r\n", "ENRX \r\n", "TXDA 01\r\n"}
+	if got := writesOf(serial.takeWritedAll()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Write() while asleep => wrote %q, want %q", got, want)
+	}
+
+	// a module that does not answer ENRX stays asleep
+	serial.setRespData([]byte("OK\r\n"))
+	im.Sleep()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := im.Wake(ctx); err == nil {
+		t.Errorf("Wake() without answer => nil, want error")
+	}
+}
+
+func writesOf(all [][]byte) []string {
+	strs := make([]string, len(all))
+	for i, v := range all {
+		strs[i] = string(v)
+	}
+
+	return strs
+}
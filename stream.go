@@ -0,0 +1,780 @@
+package im920
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Dial and Listen carry an ordered, reliable byte stream between two
+// modules. Every TXDA of a stream is a segment of
+//
+//	type(1) flags(1) destination ID(2) connection ID(2) seq(2) ack(2) window(1)
+//
+// followed by up to maxSegmentData bytes. seq numbers segments, not
+// bytes; every data segment and the FIN take one. ack is the next seq
+// the sender of the segment expects and window how many more segments
+// it can buffer. Segments not acknowledged in time are all sent again
+// (go-back-N), doubling the timeout each time.
+//
+// The dialer picks the connection ID and sets flagDialer on everything
+// it sends, so that two modules dialing each other never mix up their
+// connections.
+const (
+	frameStream byte = 0xF4
+
+	flagSYN    byte = 0x01
+	flagACK    byte = 0x02
+	flagFIN    byte = 0x04
+	flagRST    byte = 0x08
+	flagDialer byte = 0x10
+
+	segmentHeaderLen = 11
+	maxSegmentData   = maxTXDA - segmentHeaderLen
+
+	streamWindow  = 8
+	streamRecvBuf = streamWindow * maxSegmentData
+	streamSendBuf = 4096
+	streamRetries = 6
+	streamRTO     = defaultAckTimeout
+	maxStreamRTO  = 4 * time.Second
+	streamTick    = 20 * time.Millisecond
+	streamLinger  = 2 * maxStreamRTO
+	acceptBacklog = 8
+)
+
+type segment struct {
+	flags byte
+	dst   Id
+	conn  uint16
+	seq   uint16
+	ack   uint16
+	wnd   uint8
+	data  []byte
+}
+
+func encodeSegment(s segment) []byte {
+	b := make([]byte, segmentHeaderLen, segmentHeaderLen+len(s.data))
+	b[0] = frameStream
+	b[1] = s.flags
+	binary.BigEndian.PutUint16(b[2:4], uint16(s.dst))
+	binary.BigEndian.PutUint16(b[4:6], s.conn)
+	binary.BigEndian.PutUint16(b[6:8], s.seq)
+	binary.BigEndian.PutUint16(b[8:10], s.ack)
+	b[10] = s.wnd
+
+	return append(b, s.data...)
+}
+
+func decodeSegment(b []byte) (s segment, ok bool) {
+	if len(b) < segmentHeaderLen || b[0] != frameStream {
+		return
+	}
+
+	s.flags = b[1]
+	s.dst = Id(binary.BigEndian.Uint16(b[2:4]))
+	s.conn = binary.BigEndian.Uint16(b[4:6])
+	s.seq = binary.BigEndian.Uint16(b[6:8])
+	s.ack = binary.BigEndian.Uint16(b[8:10])
+	s.wnd = b[10]
+	s.data = b[segmentHeaderLen:]
+
+	return s, true
+}
+
+// seqBefore reports whether a comes before b, allowing for wraparound.
+func seqBefore(a, b uint16) bool {
+	return int16(a-b) < 0
+}
+
+type sentSegment struct {
+	seg    segment
+	sentAt time.Time
+}
+
+// Conn is one end of a stream. It implements net.Conn.
+type Conn struct {
+	im      *IM920
+	local   *Addr
+	remote  *Addr
+	id      uint16
+	dialer  bool
+	pkts    <-chan Packet
+	cancel  func()
+	kick    chan struct{}
+	rd      *deadline
+	wd      *deadline
+	flushed chan struct{}
+	done    chan struct{}
+	onDone  func()
+
+	m            sync.Mutex
+	wake         chan struct{}
+	err          error
+	closing      bool
+	isFlushed    bool
+	sendBuf      []byte
+	unacked      []sentSegment
+	nextSeq      uint16
+	peerWnd      int
+	rto          time.Duration
+	retries      int
+	finSent      bool
+	finAcked     bool
+	readBuf      []byte
+	ooo          map[uint16]segment
+	rcvNext      uint16
+	finRcvd      bool
+	ackNeeded    bool
+	synAckNeeded bool
+	rstNeeded    bool
+	lingerUntil  time.Time
+}
+
+var _ net.Conn = (*Conn)(nil)
+
+func newConn(im *IM920, local, remote *Addr, id uint16, dialer bool) *Conn {
+	pkts, cancel := im.Subscribe(maxRcvedData)
+
+	return &Conn{
+		im:      im,
+		local:   local,
+		remote:  remote,
+		id:      id,
+		dialer:  dialer,
+		pkts:    pkts,
+		cancel:  cancel,
+		kick:    make(chan struct{}, 1),
+		rd:      newDeadline(),
+		wd:      newDeadline(),
+		flushed: make(chan struct{}),
+		done:    make(chan struct{}),
+		wake:    make(chan struct{}),
+		rto:     streamRTO,
+		ooo:     make(map[uint16]segment),
+	}
+}
+
+// Dial connects to the module peer, which must be listening.
+func Dial(im *IM920, peer Id) (net.Conn, error) {
+	return DialContext(context.Background(), im, peer)
+}
+
+func DialContext(ctx context.Context, im *IM920, peer Id) (net.Conn, error) {
+	remote := &Addr{Id: peer}
+	opError := func(err error) error {
+		return &net.OpError{Op: "dial", Net: network, Addr: remote, Err: err}
+	}
+
+	id, err := im.GetIdContext(ctx)
+	if err != nil {
+		return nil, opError(fmt.Errorf("error: GetId failed: %w", err))
+	}
+
+	c := newConn(im, &Addr{Id: id}, remote, uint16(rand.Uint32()), true)
+	syn := encodeSegment(c.stamp(segment{flags: flagSYN}))
+	wait := c.rto
+
+	for attempt := 0; attempt <= streamRetries; attempt++ {
+		if _, err := im.WriteContext(ctx, syn); err != nil {
+			c.cancel()
+			return nil, opError(err)
+		}
+
+		timer := time.NewTimer(wait)
+	recv:
+		for {
+			select {
+			case pkt, ok := <-c.pkts:
+				if !ok {
+					timer.Stop()
+					return nil, opError(ErrClosed)
+				}
+				s, ok := c.accept(pkt)
+				switch {
+				case !ok:
+				case s.flags&flagRST != 0:
+					timer.Stop()
+					c.cancel()
+					return nil, opError(ErrConnReset)
+				case s.flags&(flagSYN|flagACK) == flagSYN|flagACK:
+					timer.Stop()
+					c.peerWnd = int(s.wnd)
+					go c.run()
+					return c, nil
+				}
+			case <-ctx.Done():
+				timer.Stop()
+				c.cancel()
+				return nil, opError(ctx.Err())
+			case <-timer.C:
+				break recv
+			}
+		}
+		wait *= 2
+		if wait > maxStreamRTO {
+			wait = maxStreamRTO
+		}
+	}
+
+	c.cancel()
+
+	return nil, opError(ErrNotAcknowledged)
+}
+
+// accept reports whether pkt is a segment of c.
+func (c *Conn) accept(pkt Packet) (s segment, ok bool) {
+	s, ok = decodeSegment(pkt.Data)
+	if !ok || pkt.FromId != c.remote.Id || s.dst != c.local.Id || s.conn != c.id {
+		return s, false
+	}
+
+	return s, (s.flags&flagDialer != 0) != c.dialer
+}
+
+// stamp fills in the addressing and the current ack and window of s.
+// Everything but a SYN carries an ACK.
+func (c *Conn) stamp(s segment) segment {
+	if s.flags&flagSYN == 0 {
+		s.flags |= flagACK
+	}
+	if c.dialer {
+		s.flags |= flagDialer
+	}
+	s.dst = c.remote.Id
+	s.conn = c.id
+	s.ack = c.rcvNext
+	s.wnd = uint8(c.window())
+
+	return s
+}
+
+func (c *Conn) window() int {
+	if c.closing {
+		return streamWindow
+	}
+
+	w := (streamRecvBuf - len(c.readBuf)) / maxSegmentData
+	if w > streamWindow {
+		w = streamWindow
+	}
+
+	return w
+}
+
+// signal wakes up Read and Write. c.m must be held.
+func (c *Conn) signal() {
+	close(c.wake)
+	c.wake = make(chan struct{})
+}
+
+func (c *Conn) kickRun() {
+	select {
+	case c.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (c *Conn) markFlushed() {
+	if !c.isFlushed {
+		c.isFlushed = true
+		close(c.flushed)
+	}
+}
+
+func (c *Conn) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+	c.signal()
+	c.markFlushed()
+}
+
+// abort resets the connection.
+func (c *Conn) abort() {
+	c.m.Lock()
+	c.rstNeeded = true
+	c.fail(net.ErrClosed)
+	c.m.Unlock()
+	c.kickRun()
+}
+
+func (c *Conn) run() {
+	defer func() {
+		c.cancel()
+		c.m.Lock()
+		c.markFlushed()
+		c.m.Unlock()
+		close(c.done)
+		if c.onDone != nil {
+			c.onDone()
+		}
+	}()
+
+	ticker := time.NewTicker(streamTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case pkt, ok := <-c.pkts:
+			if !ok {
+				c.m.Lock()
+				c.fail(ErrClosed)
+				c.m.Unlock()
+				return
+			}
+			if s, ok := c.accept(pkt); ok {
+				c.m.Lock()
+				c.handle(s)
+				c.m.Unlock()
+			}
+		case <-c.kick:
+		case <-ticker.C:
+		}
+
+		c.m.Lock()
+		segs, finished := c.output(time.Now())
+		c.m.Unlock()
+
+		// a lost segment is recovered by retransmission, so errors are
+		// not fatal here; a closed IM920 ends the subscription instead
+		for _, s := range segs {
+			c.im.Write(encodeSegment(s))
+		}
+		if finished {
+			return
+		}
+	}
+}
+
+func (c *Conn) handle(s segment) {
+	// the peer is alive, whatever it sent
+	c.retries = 0
+
+	if s.flags&flagRST != 0 {
+		c.fail(ErrConnReset)
+		return
+	}
+	if s.flags&flagSYN != 0 {
+		if !c.dialer {
+			c.synAckNeeded = true
+		}
+		return
+	}
+	if s.flags&flagACK != 0 {
+		c.handleAck(s)
+	}
+	if len(s.data) == 0 && s.flags&flagFIN == 0 {
+		return
+	}
+
+	c.ackNeeded = true
+	if !c.lingerUntil.IsZero() && len(s.data) > 0 && !seqBefore(s.seq, c.rcvNext) {
+		// nobody is going to read this
+		c.rstNeeded = true
+		return
+	}
+	if c.finRcvd || seqBefore(s.seq, c.rcvNext) || !seqBefore(s.seq, c.rcvNext+uint16(c.window())) {
+		return
+	}
+
+	c.ooo[s.seq] = s
+	for {
+		next, ok := c.ooo[c.rcvNext]
+		if !ok {
+			break
+		}
+		delete(c.ooo, c.rcvNext)
+		c.rcvNext++
+		if next.flags&flagFIN != 0 {
+			c.finRcvd = true
+			c.ooo = make(map[uint16]segment)
+			break
+		}
+		if !c.closing {
+			c.readBuf = append(c.readBuf, next.data...)
+		}
+	}
+	c.signal()
+}
+
+func (c *Conn) handleAck(s segment) {
+	if seqBefore(c.nextSeq, s.ack) {
+		return
+	}
+
+	c.peerWnd = int(s.wnd)
+
+	acked := false
+	for len(c.unacked) > 0 && seqBefore(c.unacked[0].seg.seq, s.ack) {
+		if c.unacked[0].seg.flags&flagFIN != 0 {
+			c.finAcked = true
+		}
+		c.unacked = c.unacked[1:]
+		acked = true
+	}
+	if acked {
+		c.rto = streamRTO
+		c.signal()
+	}
+}
+
+// output returns the segments to send now and whether the connection is
+// finished.
+func (c *Conn) output(now time.Time) (segs []segment, finished bool) {
+	if c.err != nil || !c.lingerUntil.IsZero() {
+		if c.rstNeeded {
+			c.rstNeeded = false
+			return []segment{c.stamp(segment{flags: flagRST})}, true
+		}
+		if c.err != nil {
+			return nil, true
+		}
+		if c.ackNeeded {
+			c.ackNeeded = false
+			segs = append(segs, c.stamp(segment{}))
+		}
+		return segs, now.After(c.lingerUntil)
+	}
+
+	if c.synAckNeeded {
+		c.synAckNeeded = false
+		segs = append(segs, segment{flags: flagSYN | flagACK})
+	}
+
+	if len(c.unacked) > 0 && now.Sub(c.unacked[0].sentAt) >= c.rto {
+		c.retries++
+		if c.retries > streamRetries {
+			c.fail(fmt.Errorf("error: %d retransmissions failed: %w", streamRetries, ErrNotAcknowledged))
+			return nil, true
+		}
+		c.rto *= 2
+		if c.rto > maxStreamRTO {
+			c.rto = maxStreamRTO
+		}
+		for i := range c.unacked {
+			c.unacked[i].sentAt = now
+			segs = append(segs, c.unacked[i].seg)
+		}
+	}
+
+	wnd := c.peerWnd
+	if wnd == 0 && len(c.unacked) == 0 {
+		// probe a closed window
+		wnd = 1
+	}
+	if wnd > streamWindow {
+		wnd = streamWindow
+	}
+
+	sent := false
+	for len(c.unacked) < wnd && !c.finSent {
+		var s segment
+		if len(c.sendBuf) > 0 {
+			n := len(c.sendBuf)
+			if n > maxSegmentData {
+				n = maxSegmentData
+			}
+			s.data = append([]byte(nil), c.sendBuf[:n]...)
+			c.sendBuf = c.sendBuf[n:]
+		} else if c.closing {
+			s.flags = flagFIN
+			c.finSent = true
+		} else {
+			break
+		}
+		s.seq = c.nextSeq
+		c.nextSeq++
+		c.unacked = append(c.unacked, sentSegment{seg: s, sentAt: now})
+		segs = append(segs, s)
+		sent = true
+	}
+	if sent {
+		c.signal()
+	}
+
+	if c.ackNeeded && len(segs) == 0 {
+		segs = append(segs, segment{})
+	}
+	c.ackNeeded = false
+
+	for i := range segs {
+		segs[i] = c.stamp(segs[i])
+	}
+
+	if c.finAcked {
+		c.markFlushed()
+		c.lingerUntil = now.Add(streamLinger)
+	}
+
+	return segs, false
+}
+
+func (c *Conn) opError(op string, err error) error {
+	return &net.OpError{Op: op, Net: network, Source: c.local, Addr: c.remote, Err: err}
+}
+
+// wait blocks until the state of c changes or d expires. c.m must be
+// held; it is released while waiting.
+func (c *Conn) wait(d *deadline) error {
+	wake := c.wake
+	c.m.Unlock()
+	defer c.m.Lock()
+
+	t, changed := d.get()
+	expired, stop := deadlineTimer(t)
+	defer stop()
+
+	select {
+	case <-wake:
+	case <-changed:
+	case <-expired:
+		return os.ErrDeadlineExceeded
+	}
+
+	return nil
+}
+
+func (c *Conn) Read(p []byte) (n int, err error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	for {
+		switch {
+		case c.closing:
+			return 0, c.opError("read", net.ErrClosed)
+		case len(c.readBuf) > 0:
+			wasClosed := c.window() == 0
+			n = copy(p, c.readBuf)
+			c.readBuf = c.readBuf[n:]
+			if wasClosed && c.window() > 0 {
+				// tell the peer that the window has opened again
+				c.ackNeeded = true
+				c.kickRun()
+			}
+			return n, nil
+		case c.finRcvd:
+			return 0, io.EOF
+		case c.err != nil:
+			return 0, c.opError("read", c.err)
+		}
+
+		if err := c.wait(c.rd); err != nil {
+			return 0, c.opError("read", err)
+		}
+	}
+}
+
+// Write returns once p is buffered for sending, like a TCP socket.
+func (c *Conn) Write(p []byte) (n int, err error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	for len(p) > 0 {
+		switch {
+		case c.closing:
+			return n, c.opError("write", net.ErrClosed)
+		case c.err != nil:
+			return n, c.opError("write", c.err)
+		}
+
+		room := streamSendBuf - len(c.sendBuf)
+		if room > 0 {
+			if room > len(p) {
+				room = len(p)
+			}
+			c.sendBuf = append(c.sendBuf, p[:room]...)
+			p = p[room:]
+			n += room
+			c.kickRun()
+			continue
+		}
+
+		if err := c.wait(c.wd); err != nil {
+			return n, c.opError("write", err)
+		}
+	}
+
+	return
+}
+
+// Close sends everything written so far followed by a FIN and waits
+// until the peer has acknowledged it or the retransmissions give up.
+// Data still arriving from the peer is discarded.
+func (c *Conn) Close() error {
+	c.m.Lock()
+	if c.closing {
+		c.m.Unlock()
+		return c.opError("close", net.ErrClosed)
+	}
+	c.closing = true
+	c.readBuf = nil
+	c.signal()
+	c.m.Unlock()
+
+	c.kickRun()
+	<-c.flushed
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if errors.Is(c.err, ErrNotAcknowledged) {
+		return c.opError("close", c.err)
+	}
+
+	return nil
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *Conn) SetDeadline(t time.Time) error {
+	c.rd.set(t)
+	c.wd.set(t)
+
+	return nil
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.rd.set(t)
+
+	return nil
+}
+
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.wd.set(t)
+
+	return nil
+}
+
+type connKey struct {
+	peer Id
+	id   uint16
+}
+
+// Listener accepts streams dialed to an IM920. It implements
+// net.Listener.
+type Listener struct {
+	im       *IM920
+	local    *Addr
+	pkts     <-chan Packet
+	cancel   func()
+	m        sync.Mutex
+	conns    map[connKey]*Conn
+	accepted chan *Conn
+	closed   chan struct{}
+	once     sync.Once
+	done     chan struct{}
+}
+
+var _ net.Listener = (*Listener)(nil)
+
+// Listen starts accepting streams. Closing the Listener does not close
+// the streams already accepted, nor the IM920.
+func Listen(im *IM920) (*Listener, error) {
+	id, err := im.GetId()
+	if err != nil {
+		return nil, fmt.Errorf("error: GetId failed: %w", err)
+	}
+
+	pkts, cancel := im.Subscribe(maxRcvedData)
+	l := &Listener{
+		im:       im,
+		local:    &Addr{Id: id},
+		pkts:     pkts,
+		cancel:   cancel,
+		conns:    make(map[connKey]*Conn),
+		accepted: make(chan *Conn, acceptBacklog),
+		closed:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go l.run()
+
+	return l, nil
+}
+
+func (l *Listener) run() {
+	defer close(l.done)
+
+	for pkt := range l.pkts {
+		s, ok := decodeSegment(pkt.Data)
+		if !ok || s.dst != l.local.Id || s.flags&(flagSYN|flagACK|flagDialer) != flagSYN|flagDialer {
+			continue
+		}
+
+		k := connKey{peer: pkt.FromId, id: s.conn}
+		l.m.Lock()
+		_, exists := l.conns[k]
+		l.m.Unlock()
+		if exists {
+			// a retransmitted SYN; the Conn answers it itself
+			continue
+		}
+
+		c := newConn(l.im, l.local, &Addr{Id: pkt.FromId, Node: pkt.FromNode}, s.conn, false)
+		c.peerWnd = int(s.wnd)
+		c.synAckNeeded = true
+		c.onDone = func() {
+			l.m.Lock()
+			delete(l.conns, k)
+			l.m.Unlock()
+		}
+
+		l.m.Lock()
+		l.conns[k] = c
+		l.m.Unlock()
+
+		go c.run()
+
+		select {
+		case l.accepted <- c:
+		default:
+			c.abort()
+		}
+	}
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.accepted:
+		return c, nil
+	case <-l.closed:
+		return nil, &net.OpError{Op: "accept", Net: network, Addr: l.local, Err: net.ErrClosed}
+	}
+}
+
+// Close stops accepting and resets the streams not accepted yet.
+func (l *Listener) Close() error {
+	l.once.Do(func() {
+		close(l.closed)
+		l.cancel()
+		<-l.done
+
+		for {
+			select {
+			case c := <-l.accepted:
+				c.abort()
+			default:
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+func (l *Listener) Addr() net.Addr {
+	return l.local
+}
@@ -0,0 +1,255 @@
+package im920
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAir connects fakeModules: whatever one of them transmits is
+// received by all the others, unless drop says it is lost.
+type fakeAir struct {
+	m       sync.Mutex
+	modules []*fakeModule
+	sent    int
+	drop    func(n int) bool
+}
+
+// fakeModule answers RDID and TXDA like a module would.
+type fakeModule struct {
+	air    *fakeAir
+	id     Id
+	m      sync.Mutex
+	buf    []byte
+	ready  chan struct{}
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (air *fakeAir) newModule(id Id) *fakeModule {
+	f := &fakeModule{air: air, id: id, ready: make(chan struct{}, 1), closed: make(chan struct{})}
+
+	air.m.Lock()
+	air.modules = append(air.modules, f)
+	air.m.Unlock()
+
+	return f
+}
+
+func (air *fakeAir) transmit(from *fakeModule, data []byte) {
+	air.m.Lock()
+	air.sent++
+	lost := air.drop != nil && air.drop(air.sent)
+	modules := air.modules
+	air.m.Unlock()
+
+	if lost {
+		return
+	}
+	for _, f := range modules {
+		if f != from {
+			f.push(rcvedLineOf(from.id, data))
+		}
+	}
+}
+
+func (f *fakeModule) push(s string) {
+	f.m.Lock()
+	f.buf = append(f.buf, s...)
+	f.m.Unlock()
+
+	select {
+	case f.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (f *fakeModule) Read(p []byte) (n int, err error) {
+	for {
+		f.m.Lock()
+		if len(f.buf) > 0 {
+			n = copy(p, f.buf)
+			f.buf = f.buf[n:]
+			f.m.Unlock()
+			return
+		}
+		f.m.Unlock()
+
+		select {
+		case <-f.ready:
+		case <-f.closed:
+			return 0, io.EOF
+		}
+	}
+}
+
+func (f *fakeModule) Write(p []byte) (n int, err error) {
+	for _, line := range strings.Split(strings.TrimSpace(string(p)), "\r\n") {
+		switch {
+		case line == "RDID":
+			f.push(fmt.Sprintf("%04X\r\n", uint16(f.id)))
+		case strings.HasPrefix(line, "TXDA "):
+			data, _ := hex.DecodeString(strings.TrimPrefix(line, "TXDA "))
+			f.push("OK\r\n")
+			f.air.transmit(f, data)
+		default:
+			f.push("NG\r\n")
+		}
+	}
+
+	return len(p), nil
+}
+
+func (f *fakeModule) Close() error {
+	f.once.Do(func() { close(f.closed) })
+
+	return nil
+}
+
+func newStreamPair(t *testing.T, air *fakeAir) (a, b *IM920) {
+	a = New(air.newModule(0x0001))
+	b = New(air.newModule(0x0002))
+	t.Cleanup(func() {
+		a.Close()
+		b.Close()
+	})
+
+	return
+}
+
+func TestSegment(t *testing.T) {
+	in := segment{flags: flagACK | flagDialer, dst: 0x06E5, conn: 0x1234, seq: 0xFFFF, ack: 3, wnd: 8, data: []byte("hello")}
+	out, ok := decodeSegment(encodeSegment(in))
+	if !ok || out.flags != in.flags || out.dst != in.dst || out.conn != in.conn ||
+		out.seq != in.seq || out.ack != in.ack || out.wnd != in.wnd || !bytes.Equal(out.data, in.data) {
+		t.Errorf("decodeSegment(encodeSegment(%+v)) => %+v, %v", in, out, ok)
+	}
+	if len(encodeSegment(segment{data: make([]byte, maxSegmentData)})) != maxTXDA {
+		t.Errorf("encodeSegment(%d bytes) => not %d bytes", maxSegmentData, maxTXDA)
+	}
+
+	if !seqBefore(0xFFFF, 0) || seqBefore(0, 0xFFFF) || seqBefore(1, 1) {
+		t.Errorf("seqBefore() does not wrap around")
+	}
+}
+
+func testStream(t *testing.T, air *fakeAir, size int) {
+	a, b := newStreamPair(t, air)
+
+	l, err := Listen(b)
+	if err != nil {
+		t.Fatalf("Listen() => %v, want nil", err)
+	}
+	defer l.Close()
+
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer c.Close()
+
+		got, err := io.ReadAll(io.LimitReader(c, int64(size)))
+		if err != nil {
+			errc <- err
+			return
+		}
+		if !bytes.Equal(got, data) {
+			errc <- fmt.Errorf("received %d bytes, want %d bytes as sent", len(got), len(data))
+			return
+		}
+		if _, err := c.Write([]byte("done")); err != nil {
+			errc <- err
+			return
+		}
+		if n, err := c.Read(make([]byte, 1)); err != io.EOF {
+			errc <- fmt.Errorf("Read() after peer Close() => %v, %v, want 0, EOF", n, err)
+			return
+		}
+		errc <- nil
+	}()
+
+	c, err := Dial(a, 0x0002)
+	if err != nil {
+		t.Fatalf("Dial() => %v, want nil", err)
+	}
+	if c.RemoteAddr().String() != "0002" || c.LocalAddr().String() != "0001" {
+		t.Errorf("Dial() => %v -> %v, want 0001 -> 0002", c.LocalAddr(), c.RemoteAddr())
+	}
+
+	if _, err := io.Copy(c, bytes.NewReader(data)); err != nil {
+		t.Fatalf("io.Copy() => %v, want nil", err)
+	}
+	reply := make([]byte, 4)
+	if _, err := io.ReadFull(c, reply); err != nil || string(reply) != "done" {
+		t.Fatalf("ReadFull() => %q, %v, want %q, nil", reply, err, "done")
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Close() => %v, want nil", err)
+	}
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("accepted Conn => %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("accepted Conn did not finish")
+	}
+}
+
+func TestStream(t *testing.T) {
+	testStream(t, &fakeAir{}, 1000)
+}
+
+func TestStreamLoss(t *testing.T) {
+	// lose two data segments, an ACK and the FIN at some point
+	lost := map[int]bool{4: true, 7: true, 10: true, 15: true}
+	testStream(t, &fakeAir{drop: func(n int) bool { return lost[n] }}, 300)
+}
+
+func TestStreamDeadline(t *testing.T) {
+	a, b := newStreamPair(t, &fakeAir{})
+
+	l, err := Listen(b)
+	if err != nil {
+		t.Fatalf("Listen() => %v, want nil", err)
+	}
+
+	c, err := Dial(a, 0x0002)
+	if err != nil {
+		t.Fatalf("Dial() => %v, want nil", err)
+	}
+
+	c.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if _, err := c.Read(make([]byte, 1)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Read() => %v, want %v", err, os.ErrDeadlineExceeded)
+	}
+
+	l.Close()
+	if _, err := l.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Accept() after Close() => %v, want %v", err, net.ErrClosed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := DialContext(ctx, a, 0x0003); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DialContext(nobody) => %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,143 @@
+package im920
+
+import (
+	"sync"
+	"time"
+)
+
+type subscription struct {
+	ch     chan Packet
+	filter func(Packet) bool
+}
+
+// Subscribe returns a channel that receives every packet as soon as it
+// is framed, independently of Read and of other subscribers. If the
+// subscriber falls behind and its buffer fills up, the oldest packet in
+// the buffer is dropped to make room, so a slow subscriber never stalls
+// the driver or other subscribers. A buffer smaller than 1 is raised
+// to 1. Each subscriber receives its own copy of Data.
+//
+// Calling cancel stops delivery and closes the channel; Close does the
+// same for every subscription. Lines that do not parse as packets are
+// only reported through Read and ReadPacket.
+func (im *IM920) Subscribe(buffer int) (<-chan Packet, func()) {
+	return im.subscribe(buffer, nil)
+}
+
+// SubscribeNode is like Subscribe but only receives packets sent from
+// node, so that several logical devices can share one module ID.
+func (im *IM920) SubscribeNode(node Node, buffer int) (<-chan Packet, func()) {
+	return im.subscribe(buffer, func(pkt Packet) bool {
+		return pkt.FromNode == node
+	})
+}
+
+func (im *IM920) subscribe(buffer int, filter func(Packet) bool) (<-chan Packet, func()) {
+	if buffer < 1 {
+		buffer = 1
+	}
+
+	sub := &subscription{ch: make(chan Packet, buffer), filter: filter}
+
+	im.subM.Lock()
+	defer im.subM.Unlock()
+
+	if im.subsClosed {
+		close(sub.ch)
+		return sub.ch, func() {}
+	}
+	if im.subs == nil {
+		im.subs = make(map[*subscription]struct{})
+	}
+	im.subs[sub] = struct{}{}
+
+	return sub.ch, func() {
+		im.subM.Lock()
+		defer im.subM.Unlock()
+
+		if _, ok := im.subs[sub]; ok {
+			delete(im.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// OnReceive calls f for every packet, in order, from a goroutine of its
+// own so that f may block or issue commands. It follows the overflow
+// policy of Subscribe with a buffer of maxRcvedData packets. Calling
+// cancel stops further calls, even for packets already buffered.
+func (im *IM920) OnReceive(f func(Packet)) (cancel func()) {
+	ch, cancel := im.Subscribe(maxRcvedData)
+
+	return callEach(ch, cancel, f)
+}
+
+// OnReceiveNode is like OnReceive but only calls f for packets sent from
+// node. Calling it once per node routes each node to its own handler.
+func (im *IM920) OnReceiveNode(node Node, f func(Packet)) (cancel func()) {
+	ch, cancel := im.SubscribeNode(node, maxRcvedData)
+
+	return callEach(ch, cancel, f)
+}
+
+// callEach calls f for every packet from ch until cancel. Packets still
+// buffered in ch when cancel is called are dropped; only a call already
+// in progress finishes.
+func callEach(ch <-chan Packet, cancel func(), f func(Packet)) func() {
+	done := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		for pkt := range ch {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			f(pkt)
+		}
+	}()
+
+	return func() {
+		once.Do(func() {
+			close(done)
+			cancel()
+		})
+	}
+}
+
+func (im *IM920) publish(line string, at time.Time, charIO bool) {
+	im.subM.Lock()
+	defer im.subM.Unlock()
+
+	if len(im.subs) == 0 {
+		return
+	}
+
+	pkt, err := parsePacket(line, charIO, im.headers)
+	if err != nil {
+		return
+	}
+	pkt.Time = at
+
+	// every subscriber gets its own Data, so that one modifying it
+	// cannot corrupt what another keeps
+	for sub := range im.subs {
+		if sub.filter == nil || sub.filter(pkt) {
+			own := pkt
+			own.Data = append([]byte(nil), pkt.Data...)
+			pushLatest(sub.ch, own)
+		}
+	}
+}
+
+func (im *IM920) closeSubscriptions() {
+	im.subM.Lock()
+	defer im.subM.Unlock()
+
+	for sub := range im.subs {
+		close(sub.ch)
+	}
+	im.subs = nil
+	im.subsClosed = true
+}
@@ -0,0 +1,180 @@
+package im920
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSubscribe(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+
+	ch1, cancel1 := im.Subscribe(4)
+	ch2, cancel2 := im.Subscribe(1)
+	defer cancel2()
+
+	serial.setDummyData([]byte("00,06E5,B5:0A\r\n00,06E5,B5:ZZ\r\n00,06E5,B5:0B\r\n"))
+
+	for _, want := range [][]byte{{0x0A}, {0x0B}} {
+		select {
+		case pkt := <-ch1:
+			if !bytes.Equal(pkt.Data, want) || pkt.FromId != 0x06E5 {
+				t.Errorf("Subscribe() => %+v, want data = %v", pkt, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Subscribe() => no packet, want data = %v", want)
+		}
+	}
+
+	// the buffer of ch2 only holds the latest packet
+	time.Sleep(50 * time.Millisecond)
+	if len(ch2) != 1 {
+		t.Errorf("Subscribe(1) => %v packets buffered, want 1", len(ch2))
+	}
+	if pkt := <-ch2; !bytes.Equal(pkt.Data, []byte{0x0B}) {
+		t.Errorf("Subscribe(1) => %v, want the newest packet %v", pkt.Data, []byte{0x0B})
+	}
+
+	cancel1()
+	cancel1()
+	if _, ok := <-ch1; ok {
+		t.Errorf("Subscribe() after cancel => open channel, want closed")
+	}
+
+	im.Close()
+	if _, ok := <-ch2; ok {
+		t.Errorf("Subscribe() after Close() => open channel, want closed")
+	}
+	ch3, _ := im.Subscribe(1)
+	if _, ok := <-ch3; ok {
+		t.Errorf("Subscribe() on a closed IM920 => open channel, want closed")
+	}
+}
+
+func TestOnReceive(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	rcved := make(chan Packet, 2)
+	cancel := im.OnReceive(func(pkt Packet) {
+		rcved <- pkt
+	})
+	defer cancel()
+
+	serial.setDummyData([]byte("01,06E6,B6:0A\r\n"))
+
+	select {
+	case pkt := <-rcved:
+		if !bytes.Equal(pkt.Data, []byte{0x0A}) || pkt.FromNode != 0x01 {
+			t.Errorf("OnReceive() => %+v, want data = %v", pkt, []byte{0x0A})
+		}
+	case <-time.After(time.Second):
+		t.Errorf("OnReceive() => not called, want data = %v", []byte{0x0A})
+	}
+}
+
+func TestOnReceiveCancel(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	var m sync.Mutex
+	calls := 0
+	first := make(chan struct{})
+	release := make(chan struct{})
+	cancel := im.OnReceive(func(pkt Packet) {
+		m.Lock()
+		calls++
+		n := calls
+		m.Unlock()
+		if n == 1 {
+			close(first)
+			<-release
+		}
+	})
+
+	serial.setDummyData([]byte(strings.Repeat("01,06E6,B6:0A\r\n", 10)))
+	<-first
+	// let the other packets pile up behind the blocked call
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	close(release)
+	time.Sleep(50 * time.Millisecond)
+
+	m.Lock()
+	defer m.Unlock()
+	if calls != 1 {
+		t.Errorf("OnReceive() after cancel => %v calls, want 1", calls)
+	}
+}
+
+func TestSubscribeOwnData(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	ch1, cancel1 := im.Subscribe(1)
+	defer cancel1()
+	ch2, cancel2 := im.Subscribe(1)
+	defer cancel2()
+
+	serial.setDummyData([]byte("00,06E5,B5:0A\r\n"))
+
+	var pkts []Packet
+	for _, ch := range []<-chan Packet{ch1, ch2} {
+		select {
+		case pkt := <-ch:
+			pkts = append(pkts, pkt)
+		case <-time.After(time.Second):
+			t.Fatal("Subscribe() => no packet")
+		}
+	}
+
+	pkts[0].Data[0] = 0xFF
+	if !bytes.Equal(pkts[1].Data, []byte{0x0A}) {
+		t.Errorf("Subscribe() => %v after another subscriber modified its Data, want %v", pkts[1].Data, []byte{0x0A})
+	}
+}
+
+func TestSubscribeNode(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	ch1, cancel1 := im.SubscribeNode(0x01, 4)
+	defer cancel1()
+	rcved := make(chan Packet, 4)
+	cancel2 := im.OnReceiveNode(0x02, func(pkt Packet) { rcved <- pkt })
+	defer cancel2()
+
+	serial.setDummyData([]byte("01,06E5,B5:0A\r\n02,06E5,B5:0B\r\n01,06E5,B5:0C\r\n"))
+
+	for _, want := range [][]byte{{0x0A}, {0x0C}} {
+		select {
+		case pkt := <-ch1:
+			if !bytes.Equal(pkt.Data, want) || pkt.FromNode != 0x01 {
+				t.Errorf("SubscribeNode(1) => %+v, want data = %v", pkt, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("SubscribeNode(1) => no packet, want data = %v", want)
+		}
+	}
+
+	select {
+	case pkt := <-rcved:
+		if !bytes.Equal(pkt.Data, []byte{0x0B}) || pkt.FromNode != 0x02 {
+			t.Errorf("OnReceiveNode(2) => %+v, want data = [11]", pkt)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("OnReceiveNode(2) => not called")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if len(ch1) != 0 || len(rcved) != 0 {
+		t.Errorf("SubscribeNode() => packets of other nodes delivered")
+	}
+}
@@ -0,0 +1,181 @@
+package im920
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Model identifies the module variant, as detected from RDVR.
+type Model uint8
+
+const (
+	MODEL_UNKNOWN Model = iota
+	MODEL_IM920
+	MODEL_IM920C
+	MODEL_IM920XT
+	MODEL_IM920SL
+)
+
+// Group is the group number of an IM920c or IM920XT. Modules only
+// receive from senders in the same group.
+type Group uint8
+
+var modelNames = map[string]Model{
+	"IM920":   MODEL_IM920,
+	"IM920C":  MODEL_IM920C,
+	"IM920XT": MODEL_IM920XT,
+	"IM920SL": MODEL_IM920SL,
+}
+
+func (m Model) String() string {
+	switch m {
+	case MODEL_IM920:
+		return "IM920"
+	case MODEL_IM920C:
+		return "IM920c"
+	case MODEL_IM920XT:
+		return "IM920XT"
+	case MODEL_IM920SL:
+		return "IM920sL"
+	}
+
+	return "unknown"
+}
+
+func modelOf(name string) Model {
+	return modelNames[strings.ToUpper(name)]
+}
+
+// Model reports the variant detected by Open or the last GetVersion, or
+// MODEL_UNKNOWN if the version has not been read.
+func (im *IM920) Model() Model {
+	im.versionM.Lock()
+	defer im.versionM.Unlock()
+
+	if im.version == nil {
+		return MODEL_UNKNOWN
+	}
+
+	return modelOf(im.version.Model)
+}
+
+// detectModel reads the version so that the driver knows which variant
+// it talks to, and on a variant with ACK reads the character I/O and
+// ACK modes the module booted in from RPRM. A module that does not
+// answer is left undetected rather than failing Open.
+func (im *IM920) detectModel() {
+	ctx, cancel := context.WithTimeout(context.Background(), im.readTimeout)
+	defer cancel()
+
+	if _, err := im.GetVersionContext(ctx); err != nil || im.require(CAP_ACK) != nil {
+		return
+	}
+
+	rctx, rcancel := context.WithTimeout(context.Background(), im.readTimeout)
+	defer rcancel()
+
+	var s Settings
+	if resp, err := im.IssueCommandRespStrContext(rctx, "RPRM", ""); err == nil {
+		im.restoreModes(s, parseParams(resp, &s))
+	}
+}
+
+func (im *IM920) SetGroup(group Group, persist bool) error {
+	return im.SetGroupContext(context.Background(), group, persist)
+}
+
+func (im *IM920) SetGroupContext(ctx context.Context, group Group, persist bool) error {
+	if err := im.require(CAP_GROUP); err != nil {
+		return err
+	}
+
+	return im.writeEnable(ctx, persist, func() error {
+		ierr := im.IssueCommandNormalContext(ctx, "STGN", fmt.Sprintf("%02X", group))
+		if ierr != nil {
+			return fmt.Errorf("error: STGN failed: %w", ierr)
+		}
+
+		return nil
+	})
+}
+
+func (im *IM920) GetGroup() (Group, error) {
+	return im.GetGroupContext(context.Background())
+}
+
+func (im *IM920) GetGroupContext(ctx context.Context) (group Group, err error) {
+	if err = im.require(CAP_GROUP); err != nil {
+		return
+	}
+
+	rcv, ierr := im.IssueCommandRespNumContext(ctx, "RDGN", "")
+	if ierr != nil {
+		err = fmt.Errorf("error: RDGN failed: %w", ierr)
+		return
+	}
+
+	group = Group(rcv)
+
+	return
+}
+
+// SetAckMode switches acknowledged transmission on (ECAK) or off (DCAK).
+// With it on, the module answers TXDA only once the receiver has
+// acknowledged, and with NG if it did not, which Write reports as
+// ErrNotAcknowledged. Allow for this in WithReadTimeout.
+func (im *IM920) SetAckMode(enabled, persist bool) error {
+	return im.SetAckModeContext(context.Background(), enabled, persist)
+}
+
+func (im *IM920) SetAckModeContext(ctx context.Context, enabled, persist bool) error {
+	if enabled {
+		if err := im.require(CAP_ACK); err != nil {
+			return err
+		}
+	}
+
+	cmd := "DCAK"
+	if enabled {
+		cmd = "ECAK"
+	}
+
+	return im.writeEnable(ctx, persist, func() error {
+		ierr := im.IssueCommandNormalContext(ctx, cmd, "")
+		if ierr != nil {
+			return fmt.Errorf("error: %s failed: %w", cmd, ierr)
+		}
+
+		im.setAck(enabled)
+		if persist {
+			im.saveAck(enabled)
+		}
+
+		return nil
+	})
+}
+
+// GetAckMode reports whether acknowledged transmission is on, as last
+// set by SetAckMode or read from RPRM at Open, Reset and ReadSettings.
+// Firmware without RPRM cannot report the mode, so a module that was
+// switched to ECAK with persist by other means is not seen as such.
+func (im *IM920) GetAckMode() bool {
+	im.ackM.Lock()
+	defer im.ackM.Unlock()
+
+	return im.ack
+}
+
+func (im *IM920) setAck(enabled bool) {
+	im.ackM.Lock()
+	defer im.ackM.Unlock()
+
+	im.ack = enabled
+}
+
+func (im *IM920) saveAck(enabled bool) {
+	im.ackM.Lock()
+	defer im.ackM.Unlock()
+
+	im.ackSaved = enabled
+}
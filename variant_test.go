@@ -0,0 +1,128 @@
+package im920
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// ModelTests feeds each model its own RDVR answer and, in the format
+// the driver expects from that model, the answers that depend on the
+// model: a received line, which the IM920c and IM920XT print like the
+// IM920, and the TXDA reply with ECAK on. The driver makes no other
+// distinction between these models.
+var ModelTests = []struct {
+	in_version string
+	in_rcvLine string
+	out_model  Model
+	out_info   ReadInfo
+	out_group  bool
+	out_ack    bool
+}{
+	{"IM920 VER.02.05", "00,06E6,B5:0A", MODEL_IM920, ReadInfo{FromId: 0x06E6, FromRssi: 0xB5}, false, false},
+	{"IM920c VER.01.10", "01,06E6,C0:0A", MODEL_IM920C, ReadInfo{FromNode: 0x01, FromId: 0x06E6, FromRssi: 0xC0}, true, false},
+	{"IM920c VER.01.20", "01,06E6,C0:0A", MODEL_IM920C, ReadInfo{FromNode: 0x01, FromId: 0x06E6, FromRssi: 0xC0}, true, true},
+	{"IM920XT VER.01.00", "02,06E6,9A:0A", MODEL_IM920XT, ReadInfo{FromNode: 0x02, FromId: 0x06E6, FromRssi: 0x9A}, true, true},
+	// nothing is refused when the model is not known
+	{"", "00,06E6,B5:0A", MODEL_UNKNOWN, ReadInfo{FromId: 0x06E6, FromRssi: 0xB5}, true, true},
+}
+
+func TestModel(t *testing.T) {
+	for i, tt := range ModelTests {
+		serial := newFakeSerial()
+		im := newTestIM920(serial)
+
+		resp := [][]byte{[]byte(tt.in_version + "\r\n")}
+		if tt.out_ack && tt.out_model != MODEL_UNKNOWN {
+			// a variant with ACK is asked for its modes
+			resp = append(resp, []byte("NG\r\n"))
+		}
+		serial.setRespData(resp...)
+		im.detectModel()
+		if im.Model() != tt.out_model {
+			t.Errorf("[%d]Model() => %v, want %v", i, im.Model(), tt.out_model)
+		}
+
+		serial.setDummyData([]byte(tt.in_rcvLine + "\r\n"))
+		if pkt, err := im.ReadPacket(); err != nil || pkt.ReadInfo != tt.out_info || !bytes.Equal(pkt.Data, []byte{0x0A}) {
+			t.Errorf("[%d]ReadPacket() on %v => %+v, %v, want %+v", i, tt.out_model, pkt, err, tt.out_info)
+		}
+
+		serial.takeWritedAll()
+		serial.setRespData([]byte("OK\r\n"), []byte("03\r\n"))
+		err := im.SetGroup(0x03, false)
+		group, gerr := im.GetGroup()
+		got := writesOf(serial.takeWritedAll())
+		if tt.out_group {
+			if err != nil || gerr != nil || group != 0x03 {
+				t.Errorf("[%d]SetGroup/GetGroup() on %v => %v, %v, %v, want 03", i, tt.out_model, err, group, gerr)
+			}
+			if want := []string{"STGN 03\r\n", "RDGN \r\n"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("[%d]SetGroup/GetGroup() on %v => wrote %q, want %q", i, tt.out_model, got, want)
+			}
+		} else {
+			if !errors.Is(err, ErrUnsupported) || !errors.Is(gerr, ErrUnsupported) {
+				t.Errorf("[%d]SetGroup/GetGroup() on %v => %v, %v, want %v", i, tt.out_model, err, gerr, ErrUnsupported)
+			}
+			if len(got) != 0 {
+				t.Errorf("[%d]SetGroup/GetGroup() on %v => wrote %q, want nothing", i, tt.out_model, got)
+			}
+		}
+
+		// with ECAK on, NG to TXDA means the receiver did not answer
+		serial.setRespData([]byte("OK\r\n"), []byte("NG\r\n"))
+		err = im.SetAckMode(true, false)
+		if tt.out_ack != (err == nil) {
+			t.Errorf("[%d]SetAckMode(true) on %v => %v, want errorIsNil = %v", i, tt.out_model, err, tt.out_ack)
+		}
+		if !tt.out_ack {
+			// no ECAK was sent, so NG is the first answer
+			serial.setRespData([]byte("NG\r\n"))
+		}
+		_, err = im.Write([]byte{0x01})
+		if !errors.Is(err, ErrNG) || errors.Is(err, ErrNotAcknowledged) != tt.out_ack {
+			t.Errorf("[%d]Write() with NG on %v => %v, want %v = %v", i, tt.out_model, err, ErrNotAcknowledged, tt.out_ack)
+		}
+
+		im.Close()
+	}
+}
+
+// DetectModesTests answers the RPRM that detection sends to a variant
+// with ACK; the driver takes the modes the module booted in from it.
+var DetectModesTests = []struct {
+	in_version string
+	in_rprm    string
+	out_charIO bool
+	out_ack    bool
+}{
+	{"IM920XT VER.01.00", "STCH:01\r\nECIO\r\nECAK\r\n", true, true},
+	{"IM920XT VER.01.00", "STCH:01\r\nDCIO\r\nDCAK\r\n", false, false},
+	{"IM920XT VER.01.00", "NG\r\n", false, false},
+}
+
+func TestDetectModes(t *testing.T) {
+	for i, tt := range DetectModesTests {
+		serial := newFakeSerial()
+		im := newTestIM920(serial)
+
+		serial.setRespData([]byte(tt.in_version+"\r\n"), []byte(tt.in_rprm))
+		im.detectModel()
+		if got := writesOf(serial.takeWritedAll()); !reflect.DeepEqual(got, []string{"RDVR \r\n", "RPRM \r\n"}) {
+			t.Errorf("[%d]detectModel() => wrote %q, want RDVR, RPRM", i, got)
+		}
+		if im.GetCharIOMode() != tt.out_charIO || im.GetAckMode() != tt.out_ack {
+			t.Errorf("[%d]GetCharIOMode(), GetAckMode() => %v, %v, want %v, %v", i, im.GetCharIOMode(), im.GetAckMode(), tt.out_charIO, tt.out_ack)
+		}
+
+		// with ECAK read back, NG to TXDA means the receiver did not answer
+		serial.setRespData([]byte("NG\r\n"))
+		_, err := im.Write([]byte("A"))
+		if errors.Is(err, ErrNotAcknowledged) != tt.out_ack {
+			t.Errorf("[%d]Write() with NG => %v, want %v = %v", i, err, ErrNotAcknowledged, tt.out_ack)
+		}
+
+		im.Close()
+	}
+}
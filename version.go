@@ -0,0 +1,160 @@
+package im920
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version is the firmware version reported by RDVR, e.g.
+// "IM920c VER.01.20".
+type Version struct {
+	Model string
+	Major int
+	Minor int
+	Raw   string
+}
+
+type Capabilities uint
+
+const (
+	CAP_CHAR_IO   Capabilities = 1 << iota // ECIO/DCIO
+	CAP_ACK                                // acknowledged transmission
+	CAP_REPEATER                           // repeater mode
+	CAP_HIGH_BAUD                          // 57600 and 115200 bps
+	CAP_GROUP                              // group numbers
+
+	capAll = CAP_CHAR_IO | CAP_ACK | CAP_REPEATER | CAP_HIGH_BAUD | CAP_GROUP
+)
+
+var capNames = []string{"char I/O", "ACK", "repeater", "high baud rates", "group numbers"}
+
+func (c Capabilities) Has(cap Capabilities) bool {
+	return c&cap == cap
+}
+
+func (c Capabilities) String() string {
+	var names []string
+	for i, name := range capNames {
+		if c&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// capabilityTable lists, per model, the firmware version each capability
+// appeared in.
+var capabilityTable = map[string][]struct {
+	cap          Capabilities
+	major, minor int
+}{
+	"IM920": {
+		{CAP_CHAR_IO, 1, 10},
+		{CAP_REPEATER, 2, 0},
+		{CAP_HIGH_BAUD, 3, 0},
+	},
+	"IM920C": {
+		{CAP_CHAR_IO, 1, 0},
+		{CAP_REPEATER, 1, 0},
+		{CAP_HIGH_BAUD, 1, 0},
+		{CAP_GROUP, 1, 0},
+		{CAP_ACK, 1, 20},
+	},
+	"IM920XT": {
+		{CAP_CHAR_IO, 1, 0},
+		{CAP_REPEATER, 1, 0},
+		{CAP_HIGH_BAUD, 1, 0},
+		{CAP_GROUP, 1, 0},
+		{CAP_ACK, 1, 0},
+	},
+	"IM920SL": {
+		{CAP_CHAR_IO, 1, 0},
+		{CAP_HIGH_BAUD, 1, 0},
+		{CAP_GROUP, 1, 0},
+		{CAP_ACK, 1, 0},
+	},
+}
+
+// Capabilities derives what the firmware supports from its model and
+// version. Models the driver does not know are assumed to support
+// everything, leaving it to the module to answer NG.
+func (v Version) Capabilities() Capabilities {
+	table, ok := capabilityTable[strings.ToUpper(v.Model)]
+	if !ok {
+		return capAll
+	}
+
+	var c Capabilities
+	for _, e := range table {
+		if v.Major > e.major || (v.Major == e.major && v.Minor >= e.minor) {
+			c |= e.cap
+		}
+	}
+
+	return c
+}
+
+var versionRe = regexp.MustCompile(`(?i)^\s*(\S+?)\s*VER\.?\s*(\d+)\.(\d+)`)
+
+func parseVersion(s string) (v Version, err error) {
+	v.Raw = s
+
+	m := versionRe.FindStringSubmatch(s)
+	if m == nil {
+		err = fmt.Errorf("error: invalid version (%s)", s)
+		return
+	}
+
+	v.Model = m[1]
+	v.Major, _ = strconv.Atoi(m[2])
+	v.Minor, _ = strconv.Atoi(m[3])
+
+	return
+}
+
+func (im *IM920) GetVersion() (Version, error) {
+	return im.GetVersionContext(context.Background())
+}
+
+// GetVersionContext reads and parses RDVR. The driver remembers the
+// result and from then on refuses operations the firmware does not
+// support with ErrUnsupported.
+func (im *IM920) GetVersionContext(ctx context.Context) (v Version, err error) {
+	rcv, ierr := im.IssueCommandRespStrContext(ctx, "RDVR", "")
+	if ierr != nil {
+		err = fmt.Errorf("error: RDVR failed: %w", ierr)
+		return
+	}
+
+	v, err = parseVersion(rcv)
+	if err != nil {
+		return
+	}
+	im.setVersion(v)
+
+	return
+}
+
+func (im *IM920) setVersion(v Version) {
+	im.versionM.Lock()
+	defer im.versionM.Unlock()
+
+	im.version = &v
+}
+
+// require fails with ErrUnsupported if the firmware is known not to
+// support c. Before GetVersion nothing is refused.
+func (im *IM920) require(c Capabilities) error {
+	im.versionM.Lock()
+	defer im.versionM.Unlock()
+
+	if im.version == nil || im.version.Capabilities().Has(c) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s on %s", ErrUnsupported, c, im.version.Raw)
+}
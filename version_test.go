@@ -0,0 +1,65 @@
+package im920
+
+import (
+	"errors"
+	"testing"
+)
+
+var ParseVersionTests = []struct {
+	in             string
+	out            Version
+	out_caps       Capabilities
+	out_errorIsNil bool
+}{
+	{"IM920 VER.01.00", Version{"IM920", 1, 0, "IM920 VER.01.00"}, 0, true},
+	{"IM920 Ver.02.05", Version{"IM920", 2, 5, "IM920 Ver.02.05"}, CAP_CHAR_IO | CAP_REPEATER, true},
+	{"IM920c VER.01.20", Version{"IM920c", 1, 20, "IM920c VER.01.20"}, capAll, true},
+	{"IM920c VER.01.10", Version{"IM920c", 1, 10, "IM920c VER.01.10"}, CAP_CHAR_IO | CAP_REPEATER | CAP_HIGH_BAUD | CAP_GROUP, true},
+	{"IM920XT VER.01.00", Version{"IM920XT", 1, 0, "IM920XT VER.01.00"}, capAll, true},
+	{"FOO9 VER.9.9", Version{"FOO9", 9, 9, "FOO9 VER.9.9"}, capAll, true},
+	{"", Version{Raw: ""}, 0, false},
+	{"IM920", Version{Raw: "IM920"}, 0, false},
+}
+
+func TestParseVersion(t *testing.T) {
+	for i, tt := range ParseVersionTests {
+		v, err := parseVersion(tt.in)
+		if (err == nil) != tt.out_errorIsNil {
+			t.Errorf("[%d]parseVersion(%q) => %v, want errorIsNil = %v", i, tt.in, err, tt.out_errorIsNil)
+		}
+		if v != tt.out {
+			t.Errorf("[%d]parseVersion(%q) => %+v, want %+v", i, tt.in, v, tt.out)
+		}
+		if err == nil && v.Capabilities() != tt.out_caps {
+			t.Errorf("[%d]Capabilities() => %v, want %v", i, v.Capabilities(), tt.out_caps)
+		}
+	}
+}
+
+func TestGetVersion(t *testing.T) {
+	serial := newFakeSerial()
+	im := newTestIM920(serial)
+	defer im.Close()
+
+	serial.setRespData([]byte("IM920 VER.01.00\r\n"))
+	v, err := im.GetVersion()
+	if err != nil || v.Model != "IM920" || v.Major != 1 {
+		t.Fatalf("GetVersion() => %+v, %v, want IM920 1.0", v, err)
+	}
+
+	serial.takeWritedAll()
+	if err := im.SetCharIOMode(true, false); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("SetCharIOMode(true) on %s => %v, want %v", v.Raw, err, ErrUnsupported)
+	}
+	if err := im.SetBaudRate(115200, false); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("SetBaudRate(115200) on %s => %v, want %v", v.Raw, err, ErrUnsupported)
+	}
+	if got := serial.takeWritedAll(); len(got) != 0 {
+		t.Errorf("unsupported operations => wrote %q, want nothing", got)
+	}
+
+	serial.setRespData([]byte("OK\r\n"))
+	if err := im.SetCharIOMode(false, false); err != nil {
+		t.Errorf("SetCharIOMode(false) on %s => %v, want nil", v.Raw, err)
+	}
+}